@@ -0,0 +1,57 @@
+package gcert
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// BatchRequest is a single certificate to sign with BatchSign
+type BatchRequest struct {
+	// Host is a comma-separated hostnames and IPs to generate a certificate for
+	Host string
+	// Dest is the directory the certificate and key are written to
+	Dest string
+	Opts []Option
+}
+
+// BatchError holds the errors of the failed requests of BatchSign keyed by request index
+type BatchError map[int]error
+
+func (e BatchError) Error() string {
+	indexes := make([]int, 0, len(e))
+	for i := range e {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	msgs := make([]string, len(indexes))
+	for n, i := range indexes {
+		msgs[n] = fmt.Sprintf("request %d: %v", i, e[i])
+	}
+
+	return fmt.Sprintf("%d of the batch requests failed: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// BatchSign generates a certificate for each request signed by the given CA, which is
+// loaded only once. Failed requests do not stop the batch, their errors are returned
+// as a BatchError.
+func BatchSign(caCertPath, caKeyPath string, requests []BatchRequest) error {
+	ca, err := LoadCA(caCertPath, caKeyPath)
+	if err != nil {
+		return err
+	}
+
+	errs := make(BatchError)
+	for i, req := range requests {
+		if err = ca.Sign(req.Host, req.Dest, req.Opts...); err != nil {
+			errs[i] = err
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package gcert
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBatchSign(t *testing.T) {
+	caDir := t.TempDir()
+	if err := Generate("cadomain.cert", caDir, WithCA(), WithP256()); err != nil {
+		t.Fatalf("Generate() CA error = %v", err)
+	}
+
+	hosts := []string{"a.example.com", "b.example.com", "c.example.com"}
+	var requests []BatchRequest
+	for _, host := range hosts {
+		requests = append(requests, BatchRequest{Host: host, Dest: t.TempDir(), Opts: []Option{WithP256()}})
+	}
+
+	if err := BatchSign(caDir+"/cert.pem", caDir+"/key.pem", requests); err != nil {
+		t.Fatalf("BatchSign() error = %v", err)
+	}
+
+	for i, req := range requests {
+		if err := Verify(caDir+"/cert.pem", req.Dest+"/cert.pem", hosts[i]); err != nil {
+			t.Errorf("Verify() %s error = %v", hosts[i], err)
+		}
+	}
+}
+
+func TestBatchSignCollectsErrors(t *testing.T) {
+	caDir := t.TempDir()
+	if err := Generate("cadomain.cert", caDir, WithCA(), WithP256()); err != nil {
+		t.Fatalf("Generate() CA error = %v", err)
+	}
+
+	requests := []BatchRequest{
+		{Host: "a.example.com", Dest: t.TempDir(), Opts: []Option{WithP256()}},
+		{Host: "", Dest: t.TempDir(), Opts: []Option{WithP256()}},
+		{Host: "c.example.com", Dest: t.TempDir(), Opts: []Option{WithP256()}},
+	}
+
+	err := BatchSign(caDir+"/cert.pem", caDir+"/key.pem", requests)
+
+	var batchErr BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("BatchSign() error = %v, want BatchError", err)
+	}
+
+	if len(batchErr) != 1 || batchErr[1] == nil {
+		t.Errorf("BatchSign() errors = %v, want only request 1 to fail", batchErr)
+	}
+
+	for _, i := range []int{0, 2} {
+		if err = Verify(caDir+"/cert.pem", requests[i].Dest+"/cert.pem", requests[i].Host); err != nil {
+			t.Errorf("Verify() %s error = %v", requests[i].Host, err)
+		}
+	}
+
+	if err = BatchSign(caDir+"/missing.pem", caDir+"/key.pem", requests); err == nil {
+		t.Errorf("BatchSign() expected error for missing CA")
+	}
+}
@@ -0,0 +1,84 @@
+package gcert
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"path/filepath"
+)
+
+// CA is a loaded certificate authority used to sign certificates without
+// parsing its certificate and key on every call
+type CA struct {
+	Certificate *x509.Certificate
+	PrivateKey  crypto.PrivateKey
+}
+
+// LoadCA loads the CA from the given pem certificate and private key files
+func LoadCA(certPath, keyPath string) (*CA, error) {
+	cert, key, err := loadSigner(certPath, keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CA{Certificate: cert, PrivateKey: key}, nil
+}
+
+// NewCA generates a new self-signed CA in memory.
+// host is a comma-separated hostnames and IPs to generate the CA certificate for
+func NewCA(host string, opts ...Option) (*CA, error) {
+	o := initOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	o.isCA = true
+
+	cert, priv, err := generate(context.Background(), splitHosts(host), &o)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CA{Certificate: cert, PrivateKey: priv}, nil
+}
+
+// Sign generates a certificate signed by the CA. Outputs 'cert.pem' and 'key.pem'
+// into dest directory like Generate.
+// host is a comma-separated hostnames and IPs to generate a certificate for
+func (ca *CA) Sign(host, dest string, opts ...Option) error {
+	o := initOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	o.signerCert, o.signerKey = ca.Certificate, ca.PrivateKey
+
+	if err := checkOutputOptions(&o); err != nil {
+		return err
+	}
+
+	cert, priv, err := generate(context.Background(), splitHosts(host), &o)
+	if err != nil {
+		return err
+	}
+
+	_, err = writeFiles(context.Background(), dest, cert, priv, &o)
+
+	return err
+}
+
+// GeneratePair generates a CA for caHost and a certificate for leafHost signed by it.
+// Outputs 'ca_cert.pem' and 'ca_key.pem' for the CA and 'cert.pem' and 'key.pem'
+// for the leaf into dest directory. caOpts are applied to the CA and leafOpts to the
+// leaf, e.g. an RSA CA with ECDSA leaves
+func GeneratePair(caHost, leafHost, dest string, caOpts, leafOpts []Option) error {
+	caCertPath := filepath.Join(dest, "ca_cert.pem")
+	caKeyPath := filepath.Join(dest, "ca_key.pem")
+
+	caOpts = append(append([]Option{}, caOpts...), WithCA(), WithCertFileName("ca_cert.pem"), WithKeyFileName("ca_key.pem"))
+	if err := Generate(caHost, dest, caOpts...); err != nil {
+		return err
+	}
+
+	leafOpts = append(append([]Option{}, leafOpts...), WithCertFileName("cert.pem"), WithKeyFileName("key.pem"), WithSignByParent(caCertPath, caKeyPath))
+
+	return Generate(leafHost, dest, leafOpts...)
+}
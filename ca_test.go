@@ -0,0 +1,113 @@
+package gcert
+
+import (
+	"crypto/x509"
+	"testing"
+)
+
+func TestLoadCA(t *testing.T) {
+	caDir := t.TempDir()
+	if err := Generate("cadomain.cert", caDir, WithCA(), WithP256()); err != nil {
+		t.Fatalf("Generate() CA error = %v", err)
+	}
+
+	ca, err := LoadCA(caDir+"/cert.pem", caDir+"/key.pem")
+	if err != nil {
+		t.Fatalf("LoadCA() error = %v", err)
+	}
+
+	dest := t.TempDir()
+	if err = ca.Sign("test.example.com", dest, WithP256()); err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	if err = Verify(caDir+"/cert.pem", dest+"/cert.pem", "test.example.com"); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+
+	if _, err = LoadCA(caDir+"/missing.pem", caDir+"/key.pem"); err == nil {
+		t.Errorf("LoadCA() expected error for missing certificate")
+	}
+}
+
+func TestNewCA(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{
+			name: "ECDSA CA",
+			opts: []Option{WithP256()},
+		},
+		{
+			name: "Ed25519 CA",
+			opts: []Option{WithED25519()},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ca, err := NewCA("cadomain.cert", tt.opts...)
+			if err != nil {
+				t.Fatalf("NewCA() error = %v", err)
+			}
+
+			if !ca.Certificate.IsCA {
+				t.Errorf("NewCA() certificate is not a CA")
+			}
+
+			dest := t.TempDir()
+			if err = ca.Sign("test.example.com", dest, WithP256()); err != nil {
+				t.Fatalf("Sign() error = %v", err)
+			}
+
+			cert, err := ParsePemCertFile(dest + "/cert.pem")
+			if err != nil {
+				t.Fatalf("ParsePemCertFile() error = %v", err)
+			}
+
+			roots := x509.NewCertPool()
+			roots.AddCert(ca.Certificate)
+			if _, err = cert.Verify(x509.VerifyOptions{DNSName: "test.example.com", Roots: roots}); err != nil {
+				t.Errorf("Verify() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestGeneratePair(t *testing.T) {
+	dest := t.TempDir()
+	err := GeneratePair("ca.example.com", "test.example.com", dest, []Option{WithRSABits(2048)}, []Option{WithP256()})
+	if err != nil {
+		t.Fatalf("GeneratePair() error = %v", err)
+	}
+
+	if err = Verify(dest+"/ca_cert.pem", dest+"/cert.pem", "test.example.com"); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+
+	caCert, err := ParsePemCertFile(dest + "/ca_cert.pem")
+	if err != nil {
+		t.Fatalf("ParsePemCertFile() error = %v", err)
+	}
+
+	if !caCert.IsCA {
+		t.Errorf("ca_cert.pem IsCA = false, want true")
+	}
+
+	if caCert.PublicKeyAlgorithm != x509.RSA {
+		t.Errorf("ca_cert.pem PublicKeyAlgorithm = %v, want %v", caCert.PublicKeyAlgorithm, x509.RSA)
+	}
+
+	cert, err := ParsePemCertFile(dest + "/cert.pem")
+	if err != nil {
+		t.Fatalf("ParsePemCertFile() error = %v", err)
+	}
+
+	if cert.IsCA {
+		t.Errorf("cert.pem IsCA = true, want false")
+	}
+
+	if cert.PublicKeyAlgorithm != x509.ECDSA {
+		t.Errorf("cert.pem PublicKeyAlgorithm = %v, want %v", cert.PublicKeyAlgorithm, x509.ECDSA)
+	}
+}
@@ -0,0 +1,31 @@
+package gcert
+
+import (
+	"bytes"
+	"encoding/pem"
+	"fmt"
+)
+
+// WriteChain writes the certificates of the given pem files concatenated in order
+// (e.g. leaf then intermediates) into the dest file, such as a fullchain.pem
+func WriteChain(dest string, certPaths ...string) error {
+	if len(certPaths) == 0 {
+		return fmt.Errorf("no certificates given for the chain")
+	}
+
+	var chain bytes.Buffer
+	for _, path := range certPaths {
+		certs, err := ParsePemCertChainFile(path)
+		if err != nil {
+			return err
+		}
+
+		for _, cert := range certs {
+			if err = pem.Encode(&chain, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}); err != nil {
+				return fmt.Errorf("failed to encode certificate: %w", err)
+			}
+		}
+	}
+
+	return writeFile(dest, chain.Bytes(), 0644, false)
+}
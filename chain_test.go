@@ -0,0 +1,161 @@
+package gcert
+
+import (
+	"os"
+	"testing"
+)
+
+func TestWriteChain(t *testing.T) {
+	dest := t.TempDir()
+	if err := Generate("rootdomain.cert", dest, WithCA(), WithP256(), WithCertFileName("root_cert.pem"), WithKeyFileName("root_key.pem")); err != nil {
+		t.Fatalf("Generate() root error = %v", err)
+	}
+
+	if err := Generate("intermediate.cert", dest, WithCA(), WithP256(), WithCertFileName("int_cert.pem"), WithKeyFileName("int_key.pem"), WithSignByParent(dest+"/root_cert.pem", dest+"/root_key.pem")); err != nil {
+		t.Fatalf("Generate() intermediate error = %v", err)
+	}
+
+	if err := Generate("test.example.com", dest, WithP256(), WithSignByParent(dest+"/int_cert.pem", dest+"/int_key.pem")); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		certPaths []string
+		want      int
+		wantErr   bool
+	}{
+		{
+			name:      "leaf and intermediate",
+			certPaths: []string{dest + "/cert.pem", dest + "/int_cert.pem"},
+			want:      2,
+		},
+		{
+			name:      "full chain",
+			certPaths: []string{dest + "/cert.pem", dest + "/int_cert.pem", dest + "/root_cert.pem"},
+			want:      3,
+		},
+		{
+			name:    "no certificates",
+			wantErr: true,
+		},
+		{
+			name:      "missing certificate",
+			certPaths: []string{dest + "/cert.pem", dest + "/missing.pem"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := t.TempDir() + "/fullchain.pem"
+			err := WriteChain(out, tt.certPaths...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("WriteChain() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if _, err = os.Stat(out); !os.IsNotExist(err) {
+					t.Errorf("WriteChain() wrote a chain file on error")
+				}
+				return
+			}
+
+			chain, err := ParsePemCertChainFile(out)
+			if err != nil {
+				t.Fatalf("ParsePemCertChainFile() error = %v", err)
+			}
+
+			if len(chain) != tt.want {
+				t.Fatalf("chain length = %d, want %d", len(chain), tt.want)
+			}
+
+			for i, path := range tt.certPaths {
+				cert, err := ParsePemCertFile(path)
+				if err != nil {
+					t.Fatalf("ParsePemCertFile() error = %v", err)
+				}
+
+				if !chain[i].Equal(cert) {
+					t.Errorf("chain[%d] does not match %s", i, path)
+				}
+			}
+
+			if err = VerifyChain(dest+"/root_cert.pem", dest+"/cert.pem", "test.example.com", out); err != nil {
+				t.Errorf("VerifyChain() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyBundle(t *testing.T) {
+	dest := t.TempDir()
+	if err := Generate("rootdomain.cert", dest, WithCA(), WithP256(), WithCertFileName("root_cert.pem"), WithKeyFileName("root_key.pem")); err != nil {
+		t.Fatalf("Generate() root error = %v", err)
+	}
+
+	if err := Generate("intermediate.cert", dest, WithCA(), WithP256(), WithCertFileName("int_cert.pem"), WithKeyFileName("int_key.pem"), WithSignByParent(dest+"/root_cert.pem", dest+"/root_key.pem")); err != nil {
+		t.Fatalf("Generate() intermediate error = %v", err)
+	}
+
+	if err := Generate("test.example.com", dest, WithP256(), WithSignByParent(dest+"/int_cert.pem", dest+"/int_key.pem")); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if err := Generate("other.cert", dest, WithCA(), WithP256(), WithCertFileName("other_cert.pem"), WithKeyFileName("other_key.pem")); err != nil {
+		t.Fatalf("Generate() other root error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		bundle  []string
+		dnsName string
+		wantErr bool
+	}{
+		{
+			name:    "root and intermediate",
+			bundle:  []string{dest + "/root_cert.pem", dest + "/int_cert.pem"},
+			dnsName: "test.example.com",
+		},
+		{
+			name:    "intermediate before root",
+			bundle:  []string{dest + "/int_cert.pem", dest + "/root_cert.pem"},
+			dnsName: "test.example.com",
+		},
+		{
+			name:    "wrong host",
+			bundle:  []string{dest + "/root_cert.pem", dest + "/int_cert.pem"},
+			dnsName: "other.example.com",
+			wantErr: true,
+		},
+		{
+			name:    "missing intermediate",
+			bundle:  []string{dest + "/root_cert.pem"},
+			dnsName: "test.example.com",
+			wantErr: true,
+		},
+		{
+			name:    "no root",
+			bundle:  []string{dest + "/int_cert.pem"},
+			dnsName: "test.example.com",
+			wantErr: true,
+		},
+		{
+			name:    "other root",
+			bundle:  []string{dest + "/other_cert.pem", dest + "/int_cert.pem"},
+			dnsName: "test.example.com",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bundle := t.TempDir() + "/bundle.pem"
+			if err := WriteChain(bundle, tt.bundle...); err != nil {
+				t.Fatalf("WriteChain() error = %v", err)
+			}
+
+			err := VerifyBundle(bundle, dest+"/cert.pem", tt.dnsName)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("VerifyBundle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
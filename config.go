@@ -0,0 +1,185 @@
+package gcert
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"os"
+	"time"
+)
+
+// Config is the effective configuration of the options, with defaults applied (see ResolveOptions).
+// The key passphrase, the random source, the serial source and the in-memory signer of CA.Sign
+// are not included.
+type Config struct {
+	ParentCertPath      string                  `json:"parent_cert_path,omitempty"`
+	ParentKeyPath       string                  `json:"parent_key_path,omitempty"`
+	CertFileName        string                  `json:"cert_file_name"`
+	KeyFileName         string                  `json:"key_file_name"`
+	CSRFileName         string                  `json:"csr_file_name"`
+	CRLFileName         string                  `json:"crl_file_name"`
+	CombinedFileName    string                  `json:"combined_file_name,omitempty"`
+	ProvenanceFile      string                  `json:"provenance_file,omitempty"`
+	Subject             pkix.Name               `json:"subject"`
+	Issuer              *pkix.Name              `json:"issuer,omitempty"`
+	EmailSANs           []string                `json:"email_sans,omitempty"`
+	URISANs             []string                `json:"uri_sans,omitempty"`
+	IPRanges            []string                `json:"ip_ranges,omitempty"`
+	ReverseDNS          bool                    `json:"reverse_dns"`
+	KeyUsage            x509.KeyUsage           `json:"key_usage"`
+	ExtKeyUsage         []x509.ExtKeyUsage      `json:"ext_key_usage"`
+	CriticalExtKeyUsage bool                    `json:"critical_ext_key_usage"`
+	ExtraExtensions     []pkix.Extension        `json:"extra_extensions,omitempty"`
+	PolicyOIDs          []asn1.ObjectIdentifier `json:"policy_oids,omitempty"`
+	StartDate           string                  `json:"start_date,omitempty"`
+	Duration            time.Duration           `json:"duration"`
+	ValidFrom           *time.Time              `json:"valid_from,omitempty"` // nil unless WithValidFrom is given
+	NotAfter            *time.Time              `json:"not_after,omitempty"`  // nil unless WithNotAfter is given
+	ClockSkew           time.Duration           `json:"clock_skew"`
+	MaxValidity         time.Duration           `json:"max_validity"`
+	RSABits             int                     `json:"rsa_bits"`
+	InsecureRSABits     bool                    `json:"insecure_rsa_bits"`
+	Strict              bool                    `json:"strict"`
+	Curve               string                  `json:"curve,omitempty"`
+	ED25519             bool                    `json:"ed25519"`
+	CA                  bool                    `json:"ca"`
+	NoBasicConstraints  bool                    `json:"no_basic_constraints"`
+	PathLen             int                     `json:"path_len"`
+	PermittedDNSDomains []string                `json:"permitted_dns_domains,omitempty"`
+	ExcludedDNSDomains  []string                `json:"excluded_dns_domains,omitempty"`
+	PKCS1               bool                    `json:"pkcs1"`
+	SEC1                bool                    `json:"sec1"`
+	ExistingKeyPath     string                  `json:"existing_key_path,omitempty"`
+	NoKeyFile           bool                    `json:"no_key_file"`
+	ReuseKey            bool                    `json:"reuse_key"`
+	SerialNumber        *big.Int                `json:"serial_number,omitempty"` // nil for a random serial number
+	DEROutput           bool                    `json:"der_output"`
+	PEMHeaders          map[string]string       `json:"pem_headers,omitempty"`
+	CRLF                bool                    `json:"crlf"`
+	NoClobber           bool                    `json:"no_clobber"`
+	UniqueNames         bool                    `json:"unique_names"`
+	CreateDir           bool                    `json:"create_dir"`
+	CertFileMode        os.FileMode             `json:"cert_file_mode"`
+	KeyFileMode         os.FileMode             `json:"key_file_mode"`
+	SignatureAlgorithm  x509.SignatureAlgorithm `json:"signature_algorithm"`
+}
+
+// ResolveOptions applies the options on top of the defaults and returns the effective
+// configuration, e.g. to log the parameters of a generation
+func ResolveOptions(opts ...Option) Config {
+	o := initOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var serial *big.Int
+	if o.customSerial {
+		serial = o.serialNumber
+	}
+
+	return Config{
+		ParentCertPath:      o.parentCert,
+		ParentKeyPath:       o.parentKey,
+		CertFileName:        o.certFileName,
+		KeyFileName:         o.keyFileName,
+		CSRFileName:         o.csrFileName,
+		CRLFileName:         o.crlFileName,
+		CombinedFileName:    o.combinedFileName,
+		ProvenanceFile:      o.provenanceFile,
+		Subject:             o.subject,
+		Issuer:              o.issuer,
+		EmailSANs:           o.emails,
+		URISANs:             o.uris,
+		IPRanges:            o.ipRanges,
+		ReverseDNS:          o.reverseDNS,
+		KeyUsage:            o.keyUsage,
+		ExtKeyUsage:         o.extKeyUsage,
+		CriticalExtKeyUsage: o.criticalExtKeyUsage,
+		ExtraExtensions:     o.extraExtensions,
+		PolicyOIDs:          o.policyOIDs,
+		StartDate:           o.validFrom,
+		Duration:            o.validFor,
+		ValidFrom:           optionalTime(o.notBefore),
+		NotAfter:            optionalTime(o.notAfter),
+		ClockSkew:           o.clockSkew,
+		MaxValidity:         o.maxValidity,
+		RSABits:             o.rsaBits,
+		InsecureRSABits:     o.insecureRSA,
+		Strict:              o.strict,
+		Curve:               o.ecdsaCurve,
+		ED25519:             o.ed25519Key,
+		CA:                  o.isCA,
+		NoBasicConstraints:  o.noBasicConstraints,
+		PathLen:             o.pathLen,
+		PermittedDNSDomains: o.permittedDNS,
+		ExcludedDNSDomains:  o.excludedDNS,
+		PKCS1:               o.pkcs1,
+		SEC1:                o.sec1,
+		ExistingKeyPath:     o.existingKey,
+		NoKeyFile:           o.noKeyFile,
+		ReuseKey:            o.reuseKey,
+		SerialNumber:        serial,
+		DEROutput:           o.derOutput,
+		PEMHeaders:          o.pemHeaders,
+		CRLF:                o.crlf,
+		NoClobber:           o.noClobber,
+		UniqueNames:         o.uniqueNames,
+		CreateDir:           o.createDir,
+		CertFileMode:        o.certMode,
+		KeyFileMode:         o.keyMode,
+		SignatureAlgorithm:  o.signatureAlgorithm,
+	}
+}
+
+// WithConfig applies all fields of the configuration, so ResolveOptions(WithConfig(c)) returns c.
+// Options not part of Config (e.g. WithKeyPassphrase) are kept
+func WithConfig(c Config) Option {
+	return func(o *options) {
+		o.parentCert, o.parentKey = c.ParentCertPath, c.ParentKeyPath
+		o.certFileName, o.keyFileName = c.CertFileName, c.KeyFileName
+		o.csrFileName, o.crlFileName = c.CSRFileName, c.CRLFileName
+		o.combinedFileName = c.CombinedFileName
+		o.provenanceFile = c.ProvenanceFile
+		o.subject, o.issuer = c.Subject, c.Issuer
+		o.emails, o.uris, o.ipRanges = c.EmailSANs, c.URISANs, c.IPRanges
+		o.reverseDNS = c.ReverseDNS
+		o.keyUsage, o.extKeyUsage = c.KeyUsage, c.ExtKeyUsage
+		o.criticalExtKeyUsage = c.CriticalExtKeyUsage
+		o.extraExtensions = c.ExtraExtensions
+		o.policyOIDs = c.PolicyOIDs
+		o.validFrom, o.validFor = c.StartDate, c.Duration
+		o.notBefore, o.notAfter = timeValue(c.ValidFrom), timeValue(c.NotAfter)
+		o.clockSkew, o.maxValidity = c.ClockSkew, c.MaxValidity
+		o.rsaBits, o.insecureRSA = c.RSABits, c.InsecureRSABits
+		o.strict = c.Strict
+		o.ecdsaCurve, o.ed25519Key = c.Curve, c.ED25519
+		o.isCA, o.noBasicConstraints, o.pathLen = c.CA, c.NoBasicConstraints, c.PathLen
+		o.permittedDNS, o.excludedDNS = c.PermittedDNSDomains, c.ExcludedDNSDomains
+		o.pkcs1, o.sec1 = c.PKCS1, c.SEC1
+		o.existingKey, o.noKeyFile, o.reuseKey = c.ExistingKeyPath, c.NoKeyFile, c.ReuseKey
+		o.serialNumber, o.customSerial = c.SerialNumber, c.SerialNumber != nil
+		o.derOutput, o.pemHeaders, o.crlf = c.DEROutput, c.PEMHeaders, c.CRLF
+		o.noClobber, o.uniqueNames, o.createDir = c.NoClobber, c.UniqueNames, c.CreateDir
+		o.certMode, o.keyMode = c.CertFileMode, c.KeyFileMode
+		o.signatureAlgorithm = c.SignatureAlgorithm
+	}
+}
+
+// optionalTime returns nil for the zero time, which leaves it out of the JSON
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
+
+// timeValue returns the time t points to, or the zero time for nil
+func timeValue(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+
+	return *t
+}
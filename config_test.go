@@ -0,0 +1,106 @@
+package gcert
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolveOptionsDefaults(t *testing.T) {
+	c := ResolveOptions()
+
+	if c.RSABits != 2048 {
+		t.Errorf("RSABits = %d, want 2048", c.RSABits)
+	}
+
+	if c.Duration != 365*24*time.Hour {
+		t.Errorf("Duration = %v, want %v", c.Duration, 365*24*time.Hour)
+	}
+
+	if c.CertFileName != "cert.pem" || c.KeyFileName != "key.pem" {
+		t.Errorf("file names = %s, %s, want cert.pem, key.pem", c.CertFileName, c.KeyFileName)
+	}
+
+	if c.CertFileMode != 0644 || c.KeyFileMode != 0600 {
+		t.Errorf("file modes = %v, %v, want 0644, 0600", c.CertFileMode, c.KeyFileMode)
+	}
+
+	if want := []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}; !reflect.DeepEqual(c.ExtKeyUsage, want) {
+		t.Errorf("ExtKeyUsage = %v, want %v", c.ExtKeyUsage, want)
+	}
+
+	if c.SerialNumber != nil {
+		t.Errorf("SerialNumber = %v, want nil for a random serial number", c.SerialNumber)
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	// unset dates are left out instead of being written as the zero time
+	for _, field := range []string{`"valid_from"`, `"not_after"`} {
+		if strings.Contains(string(data), field) {
+			t.Errorf("Marshal() = %s, want no %s field", data, field)
+		}
+	}
+}
+
+func TestResolveOptionsRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{
+			name: "defaults",
+		},
+		{
+			name: "customized",
+			opts: []Option{
+				WithP384(),
+				WithCA(),
+				WithPathLen(1),
+				WithOrganization("Example Inc"),
+				WithCommonName("Example Root"),
+				WithDuration(90 * 24 * time.Hour),
+				WithClockSkew(time.Minute),
+				WithValidFrom(time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)),
+				WithNotAfter(time.Date(2031, time.January, 1, 0, 0, 0, 0, time.UTC)),
+				WithSerialNumber(big.NewInt(42)),
+				WithClientAuth(),
+				WithPEMHeaders(map[string]string{"Comment": "test"}),
+				WithNoClobber(),
+				WithStrict(),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := ResolveOptions(tt.opts...)
+			if got := ResolveOptions(WithConfig(want)); !reflect.DeepEqual(got, want) {
+				t.Errorf("ResolveOptions(WithConfig()) = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestGenerateWithConfig(t *testing.T) {
+	c := ResolveOptions(WithP256(), WithOrganization("Example Inc"))
+
+	cert, _, err := GenerateCert("test.example.com", WithConfig(c))
+	if err != nil {
+		t.Fatalf("GenerateCert() error = %v", err)
+	}
+
+	if cert.PublicKeyAlgorithm != x509.ECDSA {
+		t.Errorf("PublicKeyAlgorithm = %v, want %v", cert.PublicKeyAlgorithm, x509.ECDSA)
+	}
+
+	if want := []string{"Example Inc"}; !reflect.DeepEqual(cert.Subject.Organization, want) {
+		t.Errorf("Subject.Organization = %v, want %v", cert.Subject.Organization, want)
+	}
+}
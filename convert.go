@@ -0,0 +1,54 @@
+package gcert
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+)
+
+// PEMToDER converts the pem certificate file at pemPath into a DER encoded certificate file at derPath
+func PEMToDER(pemPath, derPath string) error {
+	cert, err := ParsePemCertFile(pemPath)
+	if err != nil {
+		return err
+	}
+
+	return writeFile(derPath, cert.Raw, 0644, false)
+}
+
+// DERToPEM converts the DER encoded certificate file at derPath into a pem certificate file at pemPath
+func DERToPEM(derPath, pemPath string) error {
+	der, err := os.ReadFile(derPath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return fmt.Errorf("failed to parse DER data: %w", err)
+	}
+
+	return writeFile(pemPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), 0644, false)
+}
+
+// WritePublicKey writes the public key of the pem private key file at keyPath as a
+// "PUBLIC KEY" (SubjectPublicKeyInfo) pem file at destPath
+func WritePublicKey(keyPath, destPath string) error {
+	priv, err := ParsePemKeyFile(keyPath)
+	if err != nil {
+		return err
+	}
+
+	pub := publicKey(priv)
+	if pub == nil {
+		return fmt.Errorf("unsupported private key type %T", priv)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return fmt.Errorf("unable to marshal public key: %w", err)
+	}
+
+	return writeFile(destPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644, false)
+}
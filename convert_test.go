@@ -0,0 +1,126 @@
+package gcert
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestPEMToDER(t *testing.T) {
+	dest := t.TempDir()
+	if err := Generate("test.example.com", dest, WithP256()); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if err := PEMToDER(dest+"/cert.pem", dest+"/cert.der"); err != nil {
+		t.Fatalf("PEMToDER() error = %v", err)
+	}
+
+	if err := DERToPEM(dest+"/cert.der", dest+"/roundtrip.pem"); err != nil {
+		t.Fatalf("DERToPEM() error = %v", err)
+	}
+
+	want, err := os.ReadFile(dest + "/cert.pem")
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+
+	got, err := os.ReadFile(dest + "/roundtrip.pem")
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("DERToPEM() round trip does not match the original certificate")
+	}
+
+	der, err := os.ReadFile(dest + "/cert.der")
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+
+	cert, err := ParsePemCert(want)
+	if err != nil {
+		t.Fatalf("ParsePemCert() error = %v", err)
+	}
+
+	if !bytes.Equal(der, cert.Raw) {
+		t.Errorf("PEMToDER() output does not match the certificate DER")
+	}
+}
+
+func TestPEMToDERInvalidInput(t *testing.T) {
+	dest := t.TempDir()
+	if err := Generate("test.example.com", dest, WithP256()); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if err := PEMToDER(dest+"/key.pem", dest+"/key.der"); err == nil {
+		t.Errorf("PEMToDER() expected error for a key file")
+	}
+
+	if err := DERToPEM(dest+"/cert.pem", dest+"/out.pem"); err == nil {
+		t.Errorf("DERToPEM() expected error for a pem file")
+	}
+
+	for _, name := range []string{"key.der", "out.pem"} {
+		if _, err := os.Stat(dest + "/" + name); !os.IsNotExist(err) {
+			t.Errorf("%s should not be written for invalid input", name)
+		}
+	}
+}
+
+func TestWritePublicKey(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"ECDSA", []Option{WithP256()}},
+		{"RSA", []Option{WithRSABits(2048)}},
+		{"ED25519", []Option{WithED25519()}},
+		{"PKCS1", []Option{WithRSABits(2048), WithPKCS1()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			if err := Generate("test.example.com", dest, tt.opts...); err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+
+			if err := WritePublicKey(dest+"/key.pem", dest+"/pub.pem"); err != nil {
+				t.Fatalf("WritePublicKey() error = %v", err)
+			}
+
+			data, err := os.ReadFile(dest + "/pub.pem")
+			if err != nil {
+				t.Fatalf("ReadFile() error = %v", err)
+			}
+
+			block, _ := pem.Decode(data)
+			if block == nil || block.Type != "PUBLIC KEY" {
+				t.Fatalf("WritePublicKey() did not write a PUBLIC KEY pem block")
+			}
+
+			pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				t.Fatalf("ParsePKIXPublicKey() error = %v", err)
+			}
+
+			cert, err := ParsePemCertFile(dest + "/cert.pem")
+			if err != nil {
+				t.Fatalf("ParsePemCertFile() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(pub, cert.PublicKey) {
+				t.Errorf("WritePublicKey() public key does not match the certificate")
+			}
+		})
+	}
+
+	if err := WritePublicKey(t.TempDir()+"/missing.pem", t.TempDir()+"/pub.pem"); err == nil {
+		t.Errorf("WritePublicKey() expected error for missing key file")
+	}
+}
@@ -0,0 +1,59 @@
+package gcert
+
+import (
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// GenerateCRL generates a certificate revocation list signed by the given CA listing the
+// revoked certificates. Outputs 'crl.pem' into dest directory and will overwrite existing file.
+// The next update is set by WithDuration (default 365 days).
+func GenerateCRL(caCertPath, caKeyPath, dest string, revoked []pkix.RevokedCertificate, opts ...Option) error {
+	o := initOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	caCert, caKey, err := loadSigner(caCertPath, caKeyPath)
+	if err != nil {
+		return err
+	}
+
+	// CAs generated before CRLSign was added to WithCA lack it
+	if caCert.KeyUsage&x509.KeyUsageCRLSign == 0 {
+		return fmt.Errorf("CA certificate %s does not have the CRLSign key usage required to sign revocation lists", caCertPath)
+	}
+
+	signer, ok := caKey.(crypto.Signer)
+	if !ok {
+		return fmt.Errorf("unsupported private key type: %T", caKey)
+	}
+
+	if err = checkSignatureAlgorithm(o.signatureAlgorithm, caKey); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	template := &x509.RevocationList{
+		SignatureAlgorithm:  o.signatureAlgorithm,
+		RevokedCertificates: revoked,
+		// the CRL number must increase with every new list of the CA
+		Number:     big.NewInt(now.UnixNano()),
+		ThisUpdate: now,
+		NextUpdate: now.Add(o.validFor),
+	}
+
+	derBytes, err := x509.CreateRevocationList(o.rand, template, caCert, signer)
+	if err != nil {
+		return fmt.Errorf("failed to create revocation list: %w", err)
+	}
+
+	crlPEM := pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: derBytes})
+
+	return writeFile(fmt.Sprintf("%s/%s", dest, o.crlFileName), crlPEM, o.certMode, o.noClobber)
+}
@@ -0,0 +1,169 @@
+package gcert
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateCRL(t *testing.T) {
+	tests := []struct {
+		name    string
+		revoked []*big.Int
+		opts    []Option
+	}{
+		{
+			name:    "revoked certificates",
+			revoked: []*big.Int{big.NewInt(42), big.NewInt(4242)},
+		},
+		{
+			name: "empty list",
+		},
+		{
+			name:    "with next update duration",
+			revoked: []*big.Int{big.NewInt(7)},
+			opts:    []Option{WithDuration(24 * time.Hour), WithCRLFileName("revoked.pem")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			if err := Generate("cadomain.cert", dest, WithCA(), WithP256()); err != nil {
+				t.Fatalf("Generate() CA error = %v", err)
+			}
+
+			var revoked []pkix.RevokedCertificate
+			for _, serial := range tt.revoked {
+				revoked = append(revoked, pkix.RevokedCertificate{SerialNumber: serial, RevocationTime: time.Now()})
+			}
+
+			if err := GenerateCRL(dest+"/cert.pem", dest+"/key.pem", dest, revoked, tt.opts...); err != nil {
+				t.Fatalf("GenerateCRL() error = %v", err)
+			}
+
+			o := initOptions()
+			for _, opt := range tt.opts {
+				opt(&o)
+			}
+
+			data, err := os.ReadFile(dest + "/" + o.crlFileName)
+			if err != nil {
+				t.Fatalf("ReadFile() error = %v", err)
+			}
+
+			block, _ := pem.Decode(data)
+			if block == nil || block.Type != "X509 CRL" {
+				t.Fatalf("GenerateCRL() did not write a X509 CRL PEM block")
+			}
+
+			crl, err := x509.ParseRevocationList(block.Bytes)
+			if err != nil {
+				t.Fatalf("ParseRevocationList() error = %v", err)
+			}
+
+			ca, err := ParsePemCertFile(dest + "/cert.pem")
+			if err != nil {
+				t.Fatalf("ParsePemCertFile() error = %v", err)
+			}
+
+			if err = crl.CheckSignatureFrom(ca); err != nil {
+				t.Errorf("CheckSignatureFrom() error = %v", err)
+			}
+
+			if len(crl.RevokedCertificates) != len(tt.revoked) {
+				t.Fatalf("revoked certificates = %d, want %d", len(crl.RevokedCertificates), len(tt.revoked))
+			}
+
+			for i, serial := range tt.revoked {
+				if crl.RevokedCertificates[i].SerialNumber.Cmp(serial) != 0 {
+					t.Errorf("revoked serial = %v, want %v", crl.RevokedCertificates[i].SerialNumber, serial)
+				}
+			}
+
+			if got := crl.NextUpdate.Sub(crl.ThisUpdate); got != o.validFor {
+				t.Errorf("next update after %v, want %v", got, o.validFor)
+			}
+		})
+	}
+}
+
+func TestGenerateCRLNonCA(t *testing.T) {
+	dest := t.TempDir()
+	if err := Generate("test.example.com", dest, WithP256()); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if err := GenerateCRL(dest+"/cert.pem", dest+"/key.pem", dest, nil); err == nil {
+		t.Errorf("GenerateCRL() expected error for non CA certificate")
+	}
+}
+
+func TestGenerateCRLWithoutCRLSign(t *testing.T) {
+	dest := t.TempDir()
+
+	// a CA as generated before WithCA added CRLSign
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Old CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate() error = %v", err)
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey() error = %v", err)
+	}
+
+	certPath, keyPath := dest+"/ca_cert.pem", dest+"/ca_key.pem"
+	if err = os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err = os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	err = GenerateCRL(certPath, keyPath, dest, nil)
+	if err == nil || !strings.Contains(err.Error(), "CRLSign") {
+		t.Fatalf("GenerateCRL() error = %v, want missing CRLSign error", err)
+	}
+
+	// a leaf issued by the CA before it is reissued
+	leafDir := t.TempDir()
+	if err = Generate("test.example.com", leafDir, WithP256(), WithSignByParent(certPath, keyPath)); err != nil {
+		t.Fatalf("Generate() leaf error = %v", err)
+	}
+
+	// reissuing the CA with its own key adds CRLSign and keeps the issued certificates valid
+	reissued := t.TempDir()
+	if err = Rekey(certPath, reissued, WithExistingKey(keyPath)); err != nil {
+		t.Fatalf("Rekey() error = %v", err)
+	}
+
+	if err = Verify(reissued+"/cert.pem", leafDir+"/cert.pem", "test.example.com"); err != nil {
+		t.Errorf("Verify() error = %v for a leaf issued before the reissue", err)
+	}
+
+	if err = GenerateCRL(reissued+"/cert.pem", reissued+"/key.pem", reissued, nil); err != nil {
+		t.Errorf("GenerateCRL() error = %v for the reissued CA", err)
+	}
+}
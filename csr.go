@@ -0,0 +1,146 @@
+package gcert
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+)
+
+// GenerateCSR generates a PKCS#10 certificate signing request to be signed by an external CA.
+// Outputs 'csr.pem' and 'key.pem' into dest directory and will overwrite existing files.
+// host is a comma-separated hostnames and IPs to request a certificate for
+func GenerateCSR(host, dest string, opts ...Option) error {
+	o := initOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	hosts := splitHosts(host)
+	if len(hosts) == 0 {
+		return ErrMissingHost
+	}
+
+	priv, err := privateKey(&o)
+	if err != nil {
+		return err
+	}
+
+	template := x509.CertificateRequest{
+		Subject:        o.subject,
+		EmailAddresses: o.emails,
+	}
+
+	template.DNSNames, template.IPAddresses, err = parseHosts(hosts)
+	if err != nil {
+		return err
+	}
+
+	template.URIs, err = parseURIs(o.uris)
+	if err != nil {
+		return err
+	}
+
+	derBytes, err := x509.CreateCertificateRequest(o.rand, &template, priv)
+	if err != nil {
+		return fmt.Errorf("failed to create certificate request: %w", err)
+	}
+
+	keyBlock, err := marshalPrivateKey(priv, &o)
+	if err != nil {
+		return err
+	}
+
+	csrPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: derBytes})
+	if err = writeFile(fmt.Sprintf("%s/%s", dest, o.csrFileName), csrPEM, o.certMode, o.noClobber); err != nil {
+		return err
+	}
+
+	return writeFile(fmt.Sprintf("%s/%s", dest, o.keyFileName), pem.EncodeToMemory(keyBlock), o.keyMode, o.noClobber)
+}
+
+// SignCSR signs the certificate signing request at csrPath with the given CA.
+// Outputs 'cert.pem' into dest directory and will overwrite existing file.
+// The subject, subject alternative names and public key are taken from the request.
+func SignCSR(csrPath, caCertPath, caKeyPath, dest string, opts ...Option) error {
+	o := initOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	csr, err := ParsePemCSRFile(csrPath)
+	if err != nil {
+		return err
+	}
+
+	if err = csr.CheckSignature(); err != nil {
+		return fmt.Errorf("invalid certificate request signature: %w", err)
+	}
+
+	caCert, caKey, err := loadSigner(caCertPath, caKeyPath)
+	if err != nil {
+		return err
+	}
+
+	template, err := newTemplate(csr.PublicKey, &o)
+	if err != nil {
+		return err
+	}
+
+	if err = checkSignatureAlgorithm(o.signatureAlgorithm, caKey); err != nil {
+		return err
+	}
+
+	template.AuthorityKeyId = caCert.SubjectKeyId
+	template.Subject = csr.Subject
+	template.DNSNames = csr.DNSNames
+	template.IPAddresses = csr.IPAddresses
+	template.EmailAddresses = csr.EmailAddresses
+	template.URIs = csr.URIs
+
+	if o.strict {
+		if err = checkStrict(template, csr.PublicKey, publicKey(caKey), &o); err != nil {
+			return err
+		}
+	}
+
+	if template.SerialNumber == nil {
+		if template.SerialNumber, err = nextSerialNumber(o.serialSource); err != nil {
+			return err
+		}
+	}
+
+	derBytes, err := x509.CreateCertificate(o.rand, template, caCert, csr.PublicKey, caKey)
+	if err != nil {
+		return fmt.Errorf("failed to create certificate: %w", err)
+	}
+
+	certPEM := pem.EncodeToMemory(certBlock(derBytes, &o))
+
+	return writeFile(fmt.Sprintf("%s/%s", dest, o.certFileName), certPEM, o.certMode, o.noClobber)
+}
+
+// ParsePemCSRFile parses the given pem certificate signing request file
+func ParsePemCSRFile(path string) (*x509.CertificateRequest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	return ParsePemCSR(data)
+}
+
+// ParsePemCSR parses the given pem encoded certificate signing request
+func ParsePemCSR(data []byte) (*x509.CertificateRequest, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, fmt.Errorf("failed to parse certificate request PEM")
+	}
+
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse DER data: %w", err)
+	}
+
+	return csr, nil
+}
@@ -0,0 +1,209 @@
+package gcert
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGenerateCSR(t *testing.T) {
+	tests := []struct {
+		name          string
+		host          string
+		opts          []Option
+		wantDNSNames  []string
+		wantIPs       []net.IP
+		wantEmails    []string
+		wantSubject   string
+		wantPublicKey x509.PublicKeyAlgorithm
+		wantErr       bool
+	}{
+		{
+			name:          "with no options",
+			host:          "test.example.com,10.0.0.1",
+			wantDNSNames:  []string{"test.example.com"},
+			wantIPs:       []net.IP{net.ParseIP("10.0.0.1")},
+			wantSubject:   "O=Acme Co",
+			wantPublicKey: x509.RSA,
+		},
+		{
+			name: "with Subject, EmailSAN and P256",
+			host: "test.example.com",
+			opts: []Option{
+				WithSubject(pkix.Name{CommonName: "test.example.com", Organization: []string{"Example Inc"}}),
+				WithEmailSAN("admin@example.com"),
+				WithP256(),
+			},
+			wantDNSNames:  []string{"test.example.com"},
+			wantEmails:    []string{"admin@example.com"},
+			wantSubject:   "CN=test.example.com,O=Example Inc",
+			wantPublicKey: x509.ECDSA,
+		},
+		{
+			name:          "with ED25519",
+			host:          "test.example.com",
+			opts:          []Option{WithED25519(), WithCSRFileName("request.pem")},
+			wantDNSNames:  []string{"test.example.com"},
+			wantSubject:   "O=Acme Co",
+			wantPublicKey: x509.Ed25519,
+		},
+		{
+			name:    "with missing host",
+			host:    "",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			if err := GenerateCSR(tt.host, dest, tt.opts...); (err != nil) != tt.wantErr {
+				t.Fatalf("GenerateCSR() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			o := initOptions()
+			for _, opt := range tt.opts {
+				opt(&o)
+			}
+
+			data, err := os.ReadFile(dest + "/" + o.csrFileName)
+			if err != nil {
+				t.Fatalf("ReadFile() error = %v", err)
+			}
+
+			block, _ := pem.Decode(data)
+			if block == nil || block.Type != "CERTIFICATE REQUEST" {
+				t.Fatalf("GenerateCSR() output is not a certificate request PEM")
+			}
+
+			csr, err := x509.ParseCertificateRequest(block.Bytes)
+			if err != nil {
+				t.Fatalf("ParseCertificateRequest() error = %v", err)
+			}
+
+			if err = csr.CheckSignature(); err != nil {
+				t.Errorf("CheckSignature() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(csr.DNSNames, tt.wantDNSNames) {
+				t.Errorf("DNSNames = %v, want %v", csr.DNSNames, tt.wantDNSNames)
+			}
+
+			if len(csr.IPAddresses) != len(tt.wantIPs) || (len(tt.wantIPs) > 0 && !csr.IPAddresses[0].Equal(tt.wantIPs[0])) {
+				t.Errorf("IPAddresses = %v, want %v", csr.IPAddresses, tt.wantIPs)
+			}
+
+			if !reflect.DeepEqual(csr.EmailAddresses, tt.wantEmails) {
+				t.Errorf("EmailAddresses = %v, want %v", csr.EmailAddresses, tt.wantEmails)
+			}
+
+			if csr.Subject.String() != tt.wantSubject {
+				t.Errorf("Subject = %v, want %v", csr.Subject, tt.wantSubject)
+			}
+
+			if csr.PublicKeyAlgorithm != tt.wantPublicKey {
+				t.Errorf("PublicKeyAlgorithm = %v, want %v", csr.PublicKeyAlgorithm, tt.wantPublicKey)
+			}
+
+			if _, err = ParsePemKeyFile(dest + "/key.pem"); err != nil {
+				t.Errorf("ParsePemKeyFile() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestSignCSR(t *testing.T) {
+	tests := []struct {
+		name            string
+		host            string
+		verifyDomain    string
+		opts            []Option
+		wantExtKeyUsage []x509.ExtKeyUsage
+		wantSerial      *big.Int
+		wantVerifyErr   bool
+	}{
+		{
+			name:            "with no options",
+			host:            "test.example.com",
+			verifyDomain:    "test.example.com",
+			wantExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		},
+		{
+			name:            "with ExtKeyUsage, SerialNumber and Duration",
+			host:            "test.example.com",
+			verifyDomain:    "test.example.com",
+			opts:            []Option{WithExtKeyUsage(x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth), WithSerialNumber(big.NewInt(42)), WithDuration(time.Hour)},
+			wantExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+			wantSerial:      big.NewInt(42),
+		},
+		{
+			name:            "with invalid domain",
+			host:            "test.example.com",
+			verifyDomain:    "example.com",
+			wantExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+			wantVerifyErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			if err := Generate("cadomain.cert", dest, WithCA(), WithCertFileName("ca_cert.pem"), WithKeyFileName("ca_key.pem")); err != nil {
+				t.Fatalf("Generate() CA error = %v", err)
+			}
+
+			if err := GenerateCSR(tt.host, dest, WithP256()); err != nil {
+				t.Fatalf("GenerateCSR() error = %v", err)
+			}
+
+			if err := SignCSR(dest+"/csr.pem", dest+"/ca_cert.pem", dest+"/ca_key.pem", dest, tt.opts...); err != nil {
+				t.Fatalf("SignCSR() error = %v", err)
+			}
+
+			cert, err := ParsePemCertFile(dest + "/cert.pem")
+			if err != nil {
+				t.Fatalf("ParsePemCertFile() error = %v", err)
+			}
+
+			csr, err := ParsePemCSRFile(dest + "/csr.pem")
+			if err != nil {
+				t.Fatalf("ParsePemCSRFile() error = %v", err)
+			}
+
+			if !csr.PublicKey.(*ecdsa.PublicKey).Equal(cert.PublicKey) {
+				t.Errorf("certificate public key does not match the request")
+			}
+
+			if !reflect.DeepEqual(cert.ExtKeyUsage, tt.wantExtKeyUsage) {
+				t.Errorf("ExtKeyUsage = %v, want %v", cert.ExtKeyUsage, tt.wantExtKeyUsage)
+			}
+
+			if tt.wantSerial != nil && cert.SerialNumber.Cmp(tt.wantSerial) != 0 {
+				t.Errorf("SerialNumber = %v, want %v", cert.SerialNumber, tt.wantSerial)
+			}
+
+			if err = Verify(dest+"/ca_cert.pem", dest+"/cert.pem", tt.verifyDomain); (err != nil) != tt.wantVerifyErr {
+				t.Errorf("Verify() error = %v, wantErr %v", err, tt.wantVerifyErr)
+			}
+		})
+	}
+}
+
+func TestSignCSRInvalidRequest(t *testing.T) {
+	dest := t.TempDir()
+	if err := Generate("cadomain.cert", dest, WithCA(), WithCertFileName("ca_cert.pem"), WithKeyFileName("ca_key.pem")); err != nil {
+		t.Fatalf("Generate() CA error = %v", err)
+	}
+
+	if err := SignCSR(dest+"/ca_cert.pem", dest+"/ca_cert.pem", dest+"/ca_key.pem", dest); err == nil {
+		t.Errorf("SignCSR() expected error for a certificate instead of a request")
+	}
+}
@@ -0,0 +1,30 @@
+package gcert
+
+import (
+	"time"
+)
+
+// Expiry returns the expiration time (NotAfter) of the given pem certificate file
+func Expiry(path string) (time.Time, error) {
+	cert, err := ParsePemCertFile(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return cert.NotAfter, nil
+}
+
+// IsExpired reports whether the given pem certificate file has already expired
+func IsExpired(path string) (bool, error) {
+	return ExpiresWithin(path, 0)
+}
+
+// ExpiresWithin reports whether the given pem certificate file expires within d from now
+func ExpiresWithin(path string, d time.Duration) (bool, error) {
+	notAfter, err := Expiry(path)
+	if err != nil {
+		return false, err
+	}
+
+	return time.Now().Add(d).After(notAfter), nil
+}
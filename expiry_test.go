@@ -0,0 +1,79 @@
+package gcert
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpiry(t *testing.T) {
+	tests := []struct {
+		name              string
+		duration          time.Duration
+		within            time.Duration
+		wantExpired       bool
+		wantExpiresWithin bool
+	}{
+		{
+			name:              "with expired duration",
+			duration:          1 * time.Nanosecond,
+			within:            time.Hour,
+			wantExpired:       true,
+			wantExpiresWithin: true,
+		},
+		{
+			name:              "with short duration",
+			duration:          30 * time.Minute,
+			within:            time.Hour,
+			wantExpired:       false,
+			wantExpiresWithin: true,
+		},
+		{
+			name:              "with long duration",
+			duration:          10 * 365 * 24 * time.Hour,
+			within:            30 * 24 * time.Hour,
+			wantExpired:       false,
+			wantExpiresWithin: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			if err := Generate("test.example.com", dest, WithP256(), WithDuration(tt.duration)); err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+
+			path := dest + "/cert.pem"
+			cert, err := ParsePemCertFile(path)
+			if err != nil {
+				t.Fatalf("ParsePemCertFile() error = %v", err)
+			}
+
+			notAfter, err := Expiry(path)
+			if err != nil {
+				t.Fatalf("Expiry() error = %v", err)
+			}
+
+			if !notAfter.Equal(cert.NotAfter) {
+				t.Errorf("Expiry() = %v, want %v", notAfter, cert.NotAfter)
+			}
+
+			expired, err := IsExpired(path)
+			if err != nil {
+				t.Fatalf("IsExpired() error = %v", err)
+			}
+
+			if expired != tt.wantExpired {
+				t.Errorf("IsExpired() = %v, want %v", expired, tt.wantExpired)
+			}
+
+			expiresWithin, err := ExpiresWithin(path, tt.within)
+			if err != nil {
+				t.Fatalf("ExpiresWithin() error = %v", err)
+			}
+
+			if expiresWithin != tt.wantExpiresWithin {
+				t.Errorf("ExpiresWithin() = %v, want %v", expiresWithin, tt.wantExpiresWithin)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package gcert
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+)
+
+var oidExtensionExtKeyUsage = asn1.ObjectIdentifier{2, 5, 29, 37}
+
+// oidExtensionCertificatePolicies is the certificate policies extension (RFC 5280 section 4.2.1.4)
+var oidExtensionCertificatePolicies = asn1.ObjectIdentifier{2, 5, 29, 32}
+
+// oidExtensionTLSFeature is the TLS Feature extension (RFC 7633)
+var oidExtensionTLSFeature = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 24}
+
+// mustStapleValue is the DER encoded TLS Feature list holding only status_request (5),
+// known as OCSP Must-Staple
+var mustStapleValue = []byte{0x30, 0x03, 0x02, 0x01, 0x05}
+
+// extKeyUsageOIDs are the object identifiers of the extended key usages (RFC 5280 section 4.2.1.12)
+var extKeyUsageOIDs = map[x509.ExtKeyUsage]asn1.ObjectIdentifier{
+	x509.ExtKeyUsageAny:                            {2, 5, 29, 37, 0},
+	x509.ExtKeyUsageServerAuth:                     {1, 3, 6, 1, 5, 5, 7, 3, 1},
+	x509.ExtKeyUsageClientAuth:                     {1, 3, 6, 1, 5, 5, 7, 3, 2},
+	x509.ExtKeyUsageCodeSigning:                    {1, 3, 6, 1, 5, 5, 7, 3, 3},
+	x509.ExtKeyUsageEmailProtection:                {1, 3, 6, 1, 5, 5, 7, 3, 4},
+	x509.ExtKeyUsageIPSECEndSystem:                 {1, 3, 6, 1, 5, 5, 7, 3, 5},
+	x509.ExtKeyUsageIPSECTunnel:                    {1, 3, 6, 1, 5, 5, 7, 3, 6},
+	x509.ExtKeyUsageIPSECUser:                      {1, 3, 6, 1, 5, 5, 7, 3, 7},
+	x509.ExtKeyUsageTimeStamping:                   {1, 3, 6, 1, 5, 5, 7, 3, 8},
+	x509.ExtKeyUsageOCSPSigning:                    {1, 3, 6, 1, 5, 5, 7, 3, 9},
+	x509.ExtKeyUsageMicrosoftServerGatedCrypto:     {1, 3, 6, 1, 4, 1, 311, 10, 3, 3},
+	x509.ExtKeyUsageNetscapeServerGatedCrypto:      {2, 16, 840, 1, 113730, 4, 1},
+	x509.ExtKeyUsageMicrosoftCommercialCodeSigning: {1, 3, 6, 1, 4, 1, 311, 2, 1, 22},
+	x509.ExtKeyUsageMicrosoftKernelCodeSigning:     {1, 3, 6, 1, 4, 1, 311, 61, 1, 1},
+}
+
+// criticalExtKeyUsage builds the extended key usage extension marked as critical,
+// which x509.CreateCertificate always emits as non-critical
+func criticalExtKeyUsage(usages []x509.ExtKeyUsage) (pkix.Extension, error) {
+	oids := make([]asn1.ObjectIdentifier, 0, len(usages))
+	for _, u := range usages {
+		oid, ok := extKeyUsageOIDs[u]
+		if !ok {
+			return pkix.Extension{}, fmt.Errorf("unknown extended key usage: %v", u)
+		}
+		oids = append(oids, oid)
+	}
+
+	value, err := asn1.Marshal(oids)
+	if err != nil {
+		return pkix.Extension{}, fmt.Errorf("failed to marshal extended key usage: %w", err)
+	}
+
+	return pkix.Extension{Id: oidExtensionExtKeyUsage, Critical: true, Value: value}, nil
+}
+
+// policyInformation is a PolicyInformation of the certificate policies extension without qualifiers
+type policyInformation struct {
+	Policy asn1.ObjectIdentifier
+}
+
+// certificatePolicies builds the certificate policies extension. It is not left to
+// x509.CreateCertificate since, depending on the x509usepolicies GODEBUG default of the
+// main module, that reads either PolicyIdentifiers or Policies and ignores the other
+func certificatePolicies(oids []asn1.ObjectIdentifier) (pkix.Extension, error) {
+	policies := make([]policyInformation, 0, len(oids))
+	for _, oid := range oids {
+		policies = append(policies, policyInformation{Policy: oid})
+	}
+
+	value, err := asn1.Marshal(policies)
+	if err != nil {
+		return pkix.Extension{}, fmt.Errorf("failed to marshal certificate policies: %w", err)
+	}
+
+	return pkix.Extension{Id: oidExtensionCertificatePolicies, Value: value}, nil
+}
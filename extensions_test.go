@@ -0,0 +1,209 @@
+package gcert
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"reflect"
+	"testing"
+)
+
+func TestGenerateWithCriticalExtKeyUsage(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         []Option
+		wantUsage    []x509.ExtKeyUsage
+		wantCritical bool
+		wantErr      bool
+	}{
+		{
+			name:      "default non-critical",
+			wantUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		},
+		{
+			name:         "critical server auth",
+			opts:         []Option{WithCriticalExtKeyUsage()},
+			wantUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+			wantCritical: true,
+		},
+		{
+			name:         "critical server and client auth",
+			opts:         []Option{WithCriticalExtKeyUsage(), WithClientAuth()},
+			wantUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+			wantCritical: true,
+		},
+		{
+			name:    "critical without usages",
+			opts:    []Option{WithCriticalExtKeyUsage(), WithExtKeyUsage()},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, _, err := GenerateCert("test.example.com", append([]Option{WithP256()}, tt.opts...)...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenerateCert() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if !reflect.DeepEqual(cert.ExtKeyUsage, tt.wantUsage) {
+				t.Errorf("ExtKeyUsage = %v, want %v", cert.ExtKeyUsage, tt.wantUsage)
+			}
+
+			var found int
+			for _, ext := range cert.Extensions {
+				if ext.Id.Equal(oidExtensionExtKeyUsage) {
+					found++
+					if ext.Critical != tt.wantCritical {
+						t.Errorf("extended key usage critical = %v, want %v", ext.Critical, tt.wantCritical)
+					}
+				}
+			}
+
+			if found != 1 {
+				t.Errorf("extended key usage extension found %d times, want 1", found)
+			}
+		})
+	}
+}
+
+func TestGenerateWithExtraExtension(t *testing.T) {
+	custom := pkix.Extension{
+		Id:    asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 55555, 1},
+		Value: []byte{0x0c, 0x05, 'h', 'e', 'l', 'l', 'o'},
+	}
+	other := pkix.Extension{
+		Id:       asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 55555, 2},
+		Critical: true,
+		Value:    []byte{0x05, 0x00},
+	}
+
+	cert, _, err := GenerateCert("test.example.com", WithP256(), WithExtraExtension(custom), WithExtraExtension(other))
+	if err != nil {
+		t.Fatalf("GenerateCert() error = %v", err)
+	}
+
+	for _, want := range []pkix.Extension{custom, other} {
+		var found bool
+		for _, ext := range cert.Extensions {
+			if ext.Id.Equal(want.Id) {
+				found = true
+				if !reflect.DeepEqual(ext, want) {
+					t.Errorf("extension = %+v, want %+v", ext, want)
+				}
+			}
+		}
+
+		if !found {
+			t.Errorf("extension %v not found", want.Id)
+		}
+	}
+}
+
+func TestGenerateWithMustStaple(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want bool
+	}{
+		{
+			name: "default",
+			want: false,
+		},
+		{
+			name: "with must staple",
+			opts: []Option{WithMustStaple()},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, _, err := GenerateCert("test.example.com", append([]Option{WithP256()}, tt.opts...)...)
+			if err != nil {
+				t.Fatalf("GenerateCert() error = %v", err)
+			}
+
+			var found bool
+			for _, ext := range cert.Extensions {
+				if !ext.Id.Equal(oidExtensionTLSFeature) {
+					continue
+				}
+				found = true
+
+				var features []int
+				if _, err = asn1.Unmarshal(ext.Value, &features); err != nil {
+					t.Fatalf("Unmarshal() error = %v", err)
+				}
+
+				if want := []int{5}; !reflect.DeepEqual(features, want) {
+					t.Errorf("TLS features = %v, want %v", features, want)
+				}
+
+				if ext.Critical {
+					t.Errorf("TLS Feature extension should not be critical")
+				}
+			}
+
+			if found != tt.want {
+				t.Errorf("TLS Feature extension present = %v, want %v", found, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateWithPolicyOID(t *testing.T) {
+	cps := asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 1, 1}
+	domainValidated := asn1.ObjectIdentifier{2, 23, 140, 1, 2, 1}
+	tests := []struct {
+		name string
+		opts []Option
+		want []asn1.ObjectIdentifier
+	}{
+		{
+			name: "default",
+			want: nil,
+		},
+		{
+			name: "single policy",
+			opts: []Option{WithPolicyOID(cps)},
+			want: []asn1.ObjectIdentifier{cps},
+		},
+		{
+			name: "multiple policies",
+			opts: []Option{WithPolicyOID(cps), WithPolicyOID(domainValidated)},
+			want: []asn1.ObjectIdentifier{cps, domainValidated},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, _, err := GenerateCert("test.example.com", append([]Option{WithP256()}, tt.opts...)...)
+			if err != nil {
+				t.Fatalf("GenerateCert() error = %v", err)
+			}
+
+			// read the extension itself, the parsed PolicyIdentifiers and Policies fields
+			// depend on the x509usepolicies GODEBUG default of the main module
+			var got []asn1.ObjectIdentifier
+			for _, ext := range cert.Extensions {
+				if !ext.Id.Equal(oidExtensionCertificatePolicies) {
+					continue
+				}
+
+				var policies []policyInformation
+				if rest, err := asn1.Unmarshal(ext.Value, &policies); err != nil || len(rest) > 0 {
+					t.Fatalf("failed to unmarshal certificate policies: %v", err)
+				}
+
+				for _, p := range policies {
+					got = append(got, p.Policy)
+				}
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("certificate policies = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package gcert
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"strings"
+)
+
+// Fingerprint returns the hex encoded SHA-256 fingerprint of the certificate
+func Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// FingerprintColon returns the SHA-256 fingerprint of the certificate formatted
+// as colon separated uppercase hex (e.g. AB:CD:...), as printed by openssl
+func FingerprintColon(cert *x509.Certificate) string {
+	return colonHex(Fingerprint(cert))
+}
+
+// FingerprintFile returns the hex encoded SHA-256 fingerprint of the given pem certificate file
+func FingerprintFile(path string) (string, error) {
+	cert, err := ParsePemCertFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return Fingerprint(cert), nil
+}
+
+// colonHex formats a hex string as colon separated uppercase byte pairs
+func colonHex(s string) string {
+	parts := make([]string, 0, len(s)/2)
+	for i := 0; i+1 < len(s); i += 2 {
+		parts = append(parts, strings.ToUpper(s[i:i+2]))
+	}
+
+	return strings.Join(parts, ":")
+}
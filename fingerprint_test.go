@@ -0,0 +1,35 @@
+package gcert
+
+import (
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	cert, err := ParsePemCertFile("testdata/cert.pem")
+	if err != nil {
+		t.Fatalf("ParsePemCertFile() error = %v", err)
+	}
+
+	want := "39c2e4712a06b58dc24d55214849f2b418b871d4551214f08cef8e5b20caadd3"
+	if got := Fingerprint(cert); got != want {
+		t.Errorf("Fingerprint() = %v, want %v", got, want)
+	}
+
+	wantColon := "39:C2:E4:71:2A:06:B5:8D:C2:4D:55:21:48:49:F2:B4:18:B8:71:D4:55:12:14:F0:8C:EF:8E:5B:20:CA:AD:D3"
+	if got := FingerprintColon(cert); got != wantColon {
+		t.Errorf("FingerprintColon() = %v, want %v", got, wantColon)
+	}
+
+	got, err := FingerprintFile("testdata/cert.pem")
+	if err != nil {
+		t.Fatalf("FingerprintFile() error = %v", err)
+	}
+
+	if got != want {
+		t.Errorf("FingerprintFile() = %v, want %v", got, want)
+	}
+
+	if _, err = FingerprintFile("testdata/missing.pem"); err == nil {
+		t.Errorf("FingerprintFile() expected error for missing file")
+	}
+}
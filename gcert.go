@@ -1,19 +1,29 @@
 package gcert
 
 import (
+	"bytes"
+	"context"
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha1"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/asn1"
 	"encoding/pem"
+	"errors"
 	"fmt"
+	"io"
 	"math/big"
 	"net"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -21,220 +31,1479 @@ import (
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
+var (
+	// ErrMissingHost is returned when no host is given to generate a certificate for
+	ErrMissingHost = errors.New("missing required host parameter")
+	// ErrUnrecognizedCurve is returned when the elliptic curve is not supported
+	ErrUnrecognizedCurve = errors.New("unrecognized elliptic curve")
+	// ErrEncryptedKey is returned when parsing an encrypted private key without a password,
+	// use ParseEncryptedPemKeyFile instead
+	ErrEncryptedKey = errors.New("private key is encrypted")
+	// ErrKeyMismatch is returned when a private key does not belong to the certificate
+	ErrKeyMismatch = errors.New("private key does not match certificate")
+	// ErrStrict is returned when WithStrict rejects weak crypto or an over-long validity
+	ErrStrict = errors.New("strict mode violation")
+)
+
 // Generate a self-signed X.509 certificate for a TLS server. Outputs
-// 'cert.pem' and 'key.pem' into dest directory and will overwrite existing files.
-// host is a comma-separated hostnames and IPs to generate a certificate for
+// 'cert.pem' and 'key.pem' into dest directory and will overwrite existing files
+// unless WithNoClobber is used.
+// Each file is replaced atomically, but concurrent calls writing the same file names
+// into the same directory may leave the certificate of one call next to the key of
+// another, use WithUniqueNames or distinct file names for concurrent generation.
+// host is a comma-separated hostnames and IPs to generate a certificate for, it can be
+// empty for a CA with a subject common name (see WithCommonName)
 func Generate(host, dest string, opts ...Option) error {
-	if len(host) == 0 {
-		return fmt.Errorf("missing required host parameter")
+	return GenerateContext(context.Background(), host, dest, opts...)
+}
+
+// GenerateContext is like Generate but returns early with the context error
+// if ctx is cancelled before the key is generated or a file is written
+func GenerateContext(ctx context.Context, host, dest string, opts ...Option) error {
+	o := initOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if err := checkOutputOptions(&o); err != nil {
+		return err
+	}
+
+	cert, priv, err := generate(ctx, splitHosts(host), &o)
+	if err != nil {
+		return err
+	}
+
+	_, err = writeFiles(ctx, dest, cert, priv, &o)
+
+	return err
+}
+
+// GenerateResult describes the files written by GenerateWithResult
+type GenerateResult struct {
+	CertPath    string
+	KeyPath     string // same as CertPath with WithCombinedOutput
+	Serial      *big.Int
+	NotAfter    time.Time
+	Fingerprint string // hex encoded SHA-256 fingerprint, see Fingerprint
+}
+
+// GenerateWithResult is like Generate but also returns the written file paths and
+// the serial number, expiry and fingerprint of the certificate
+func GenerateWithResult(host, dest string, opts ...Option) (*GenerateResult, error) {
+	o := initOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if err := checkOutputOptions(&o); err != nil {
+		return nil, err
+	}
+
+	cert, priv, err := generate(context.Background(), splitHosts(host), &o)
+	if err != nil {
+		return nil, err
+	}
+
+	return writeFiles(context.Background(), dest, cert, priv, &o)
+}
+
+// Validate runs everything Generate does (key generation, certificate creation and
+// encoding) without writing any files, returning the error Generate would return
+func Validate(host string, opts ...Option) error {
+	o := initOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	// a random serial number keeps the WithSerialSource source untouched
+	o.serialSource = nil
+
+	if err := checkOutputOptions(&o); err != nil {
+		return err
+	}
+
+	cert, priv, err := generate(context.Background(), splitHosts(host), &o)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = encodePEM(cert, priv, &o)
+
+	return err
+}
+
+// GenerateHosts is like Generate but takes the hostnames and IPs as a slice
+// instead of a comma-separated string, blank entries are skipped
+func GenerateHosts(hosts []string, dest string, opts ...Option) error {
+	o := initOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if err := checkOutputOptions(&o); err != nil {
+		return err
+	}
+
+	cert, priv, err := generate(context.Background(), trimHosts(hosts), &o)
+	if err != nil {
+		return err
+	}
+
+	_, err = writeFiles(context.Background(), dest, cert, priv, &o)
+
+	return err
+}
+
+// localhostHosts are the hosts of GenerateLocalhost
+var localhostHosts = []string{"localhost", "127.0.0.1", "::1"}
+
+// GenerateLocalhost is like Generate for local development, the certificate is valid
+// for localhost and the IPv4 and IPv6 loopback addresses (127.0.0.1 and ::1)
+func GenerateLocalhost(dest string, opts ...Option) error {
+	return GenerateHosts(localhostHosts, dest, opts...)
+}
+
+// checkOutputOptions rejects output options that can not be combined, before anything
+// is generated or written
+func checkOutputOptions(o *options) error {
+	if len(o.combinedFileName) > 0 && o.derOutput {
+		return fmt.Errorf("combined output is only supported for PEM encoding")
+	}
+
+	if o.noKeyFile {
+		// without the key file a freshly generated key would be lost
+		if len(o.existingKey) == 0 {
+			return fmt.Errorf("skipping the key file requires an existing key (see WithExistingKey)")
+		}
+
+		if len(o.combinedFileName) > 0 {
+			return fmt.Errorf("combined output always contains the key")
+		}
+	}
+
+	return nil
+}
+
+// writeFiles writes the PEM (or DER with WithDEROutput) encoded certificate and private key into dest
+// directory, followed by the provenance file of WithProvenanceFile
+func writeFiles(ctx context.Context, dest string, cert *x509.Certificate, priv any, o *options) (*GenerateResult, error) {
+	if o.createDir {
+		if err := createDir(dest); err != nil {
+			return nil, err
+		}
+	}
+
+	names := *o
+	names.certFileName = expandFileName(o.certFileName, cert)
+	names.keyFileName = expandFileName(o.keyFileName, cert)
+	names.combinedFileName = expandFileName(o.combinedFileName, cert)
+	if o.uniqueNames {
+		suffix := cert.SerialNumber.Text(16)
+		names.certFileName = uniqueFileName(names.certFileName, suffix)
+		names.keyFileName = uniqueFileName(names.keyFileName, suffix)
+		names.combinedFileName = uniqueFileName(names.combinedFileName, suffix)
+	}
+	o = &names
+
+	// checked before the key pair is written, which could not be retried once it exists
+	if o.noClobber && len(o.provenanceFile) > 0 {
+		if _, err := os.Stat(o.provenanceFile); err == nil {
+			return nil, fmt.Errorf("refusing to overwrite existing file %s", o.provenanceFile)
+		}
+	}
+
+	var certPath, keyPath string
+	var err error
+	if o.noKeyFile {
+		certPath, err = writeCertFile(ctx, dest, cert, o)
+	} else if len(o.combinedFileName) > 0 {
+		certPath, err = writeCombinedFile(ctx, dest, cert, priv, o)
+		keyPath = certPath
+	} else {
+		certPath, keyPath, err = writeKeyPair(ctx, dest, cert, priv, o)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(o.provenanceFile) > 0 {
+		if err = writeProvenance(o.provenanceFile, cert, o); err != nil {
+			return nil, err
+		}
+	}
+
+	return &GenerateResult{
+		CertPath:    certPath,
+		KeyPath:     keyPath,
+		Serial:      cert.SerialNumber,
+		NotAfter:    cert.NotAfter,
+		Fingerprint: Fingerprint(cert),
+	}, nil
+}
+
+// createDir creates the dest directory and its parents if missing
+func createDir(dest string) error {
+	if info, err := os.Stat(dest); err == nil && !info.IsDir() {
+		return fmt.Errorf("destination %s exists and is not a directory", dest)
+	}
+
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return fmt.Errorf("failed to create destination directory: %w", err)
+	}
+
+	return nil
+}
+
+// writeKeyPair writes the certificate and private key into separate files in dest directory
+// and returns their paths
+func writeKeyPair(ctx context.Context, dest string, cert *x509.Certificate, priv any, o *options) (string, string, error) {
+	certFileName, keyFileName := o.certFileName, o.keyFileName
+	var certOut, keyOut []byte
+	if o.derOutput {
+		keyBlock, err := marshalPrivateKey(priv, o)
+		if err != nil {
+			return "", "", err
+		}
+
+		certOut, keyOut = cert.Raw, keyBlock.Bytes
+		certFileName, keyFileName = derFileName(certFileName), derFileName(keyFileName)
+	} else {
+		var err error
+		certOut, keyOut, err = encodePEM(cert, priv, o)
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	certPath := fmt.Sprintf("%s/%s", dest, certFileName)
+	keyPath := fmt.Sprintf("%s/%s", dest, keyFileName)
+	if o.noClobber {
+		// check both files up front so the certificate is not written when only the key exists
+		for _, path := range []string{certPath, keyPath} {
+			if _, err := os.Stat(path); err == nil {
+				return "", "", fmt.Errorf("refusing to overwrite existing file %s", path)
+			}
+		}
+	}
+
+	// stage both files before moving either into place so a failure never leaves
+	// a new certificate next to an old key
+	if err := ctx.Err(); err != nil {
+		return "", "", err
+	}
+
+	certTmp, err := writeTemp(certPath, certOut, o.certMode)
+	if err != nil {
+		return "", "", err
+	}
+
+	if err = ctx.Err(); err != nil {
+		os.Remove(certTmp)
+		return "", "", err
+	}
+
+	keyTmp, err := writeTemp(keyPath, keyOut, o.keyMode)
+	if err != nil {
+		os.Remove(certTmp)
+		return "", "", err
+	}
+
+	if err = commitFile(certTmp, certPath, o.noClobber); err != nil {
+		os.Remove(keyTmp)
+		return "", "", err
+	}
+
+	if err = commitFile(keyTmp, keyPath, o.noClobber); err != nil {
+		return "", "", err
+	}
+
+	return certPath, keyPath, nil
+}
+
+// writeCertFile writes only the certificate into dest directory and returns its path
+func writeCertFile(ctx context.Context, dest string, cert *x509.Certificate, o *options) (string, error) {
+	certFileName, certOut := o.certFileName, cert.Raw
+	if o.derOutput {
+		certFileName = derFileName(certFileName)
+	} else {
+		certOut = pem.EncodeToMemory(certBlock(cert.Raw, o))
+		if o.crlf {
+			certOut = toCRLF(certOut)
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	path := fmt.Sprintf("%s/%s", dest, certFileName)
+	if err := writeFile(path, certOut, o.certMode, o.noClobber); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// writeCombinedFile writes the PEM encoded private key followed by the certificate into
+// a single file in dest directory, with the key file permissions as it contains the key,
+// and returns its path
+func writeCombinedFile(ctx context.Context, dest string, cert *x509.Certificate, priv any, o *options) (string, error) {
+	certPEM, keyPEM, err := encodePEM(cert, priv, o)
+	if err != nil {
+		return "", err
+	}
+
+	if err = ctx.Err(); err != nil {
+		return "", err
+	}
+
+	path := fmt.Sprintf("%s/%s", dest, o.combinedFileName)
+	if err = writeFile(path, append(keyPEM, certPEM...), o.keyMode, o.noClobber); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// expandFileName expands the {host} (first SAN or common name), {serial} (hex) and {date}
+// (creation date as YYYYMMDD) placeholders of the file name. Characters of the host that
+// are unsafe in file names (*, /, \ and :) are replaced with _
+func expandFileName(name string, cert *x509.Certificate) string {
+	if !strings.Contains(name, "{") {
+		return name
+	}
+
+	host := cert.Subject.CommonName
+	if len(cert.DNSNames) > 0 {
+		host = cert.DNSNames[0]
+	} else if len(cert.IPAddresses) > 0 {
+		host = cert.IPAddresses[0].String()
+	}
+	host = strings.NewReplacer("*", "_", "/", "_", "\\", "_", ":", "_").Replace(host)
+
+	return strings.NewReplacer(
+		"{host}", host,
+		"{serial}", cert.SerialNumber.Text(16),
+		"{date}", cert.NotBefore.UTC().Format("20060102"),
+	).Replace(name)
+}
+
+// uniqueFileName inserts the suffix before the extension of the file name (cert.pem -> cert-<suffix>.pem)
+func uniqueFileName(name, suffix string) string {
+	if len(name) == 0 {
+		return name
+	}
+
+	ext := filepath.Ext(name)
+	return strings.TrimSuffix(name, ext) + "-" + suffix + ext
+}
+
+// derFileName replaces the .pem extension of the file name with .der
+func derFileName(name string) string {
+	return strings.TrimSuffix(name, ".pem") + ".der"
+}
+
+// writeFile atomically writes data to the file at path with the given permissions,
+// replacing any existing file. With noClobber the file must not exist yet.
+func writeFile(path string, data []byte, perm os.FileMode, noClobber bool) error {
+	tmp, err := writeTemp(path, data, perm)
+	if err != nil {
+		return err
+	}
+
+	return commitFile(tmp, path, noClobber)
+}
+
+// writeTemp writes data to a temporary file next to path and returns its name.
+// The temporary file is removed if anything fails.
+func writeTemp(path string, data []byte, perm os.FileMode) (string, error) {
+	out, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s for writing: %w", path, err)
+	}
+
+	fail := func(format string, err error) (string, error) {
+		out.Close()
+		os.Remove(out.Name())
+		return "", fmt.Errorf(format, path, err)
+	}
+
+	if err = out.Chmod(perm); err != nil {
+		return fail("failed to set permissions of %s: %v", err)
+	}
+
+	if _, err = out.Write(data); err != nil {
+		return fail("failed to write data to %s: %v", err)
+	}
+
+	if err = out.Close(); err != nil {
+		os.Remove(out.Name())
+		return "", fmt.Errorf("error closing %s: %w", path, err)
+	}
+
+	return out.Name(), nil
+}
+
+// commitFile moves the temporary file into place at path. With noClobber it
+// links instead of renaming, which fails if path already exists.
+func commitFile(tmp, path string, noClobber bool) error {
+	if noClobber {
+		defer os.Remove(tmp)
+		if err := os.Link(tmp, path); err != nil {
+			return fmt.Errorf("failed to create %s: %w", path, err)
+		}
+
+		return nil
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to move %s into place: %w", path, err)
+	}
+
+	return nil
+}
+
+// GenerateTo generates a certificate and writes the PEM encoded certificate to
+// certOut and the private key to keyOut. Both are fully built before anything
+// is written, so an error after the certificate has been written means only
+// keyOut failed and the certificate must be discarded by the caller.
+func GenerateTo(host string, certOut, keyOut io.Writer, opts ...Option) error {
+	o := initOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	cert, priv, err := generate(context.Background(), splitHosts(host), &o)
+	if err != nil {
+		return err
+	}
+
+	certPEM, keyPEM, err := encodePEM(cert, priv, &o)
+	if err != nil {
+		return err
+	}
+
+	if _, err := certOut.Write(certPEM); err != nil {
+		return fmt.Errorf("failed to write certificate: %w", err)
+	}
+
+	if _, err := keyOut.Write(keyPEM); err != nil {
+		return fmt.Errorf("certificate was written but failed to write private key: %w", err)
+	}
+
+	return nil
+}
+
+// GenerateCert generates a certificate and its private key in memory without
+// writing any files. host is a comma-separated hostnames and IPs to generate a certificate for
+func GenerateCert(host string, opts ...Option) (*x509.Certificate, crypto.PrivateKey, error) {
+	o := initOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return generate(context.Background(), splitHosts(host), &o)
+}
+
+// GenerateDER generates a certificate in memory and returns the DER encoded certificate
+// and private key (PKCS#8 unless WithPKCS1 or WithSEC1 is given).
+// host is a comma-separated hostnames and IPs to generate a certificate for
+func GenerateDER(host string, opts ...Option) ([]byte, []byte, error) {
+	o := initOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	cert, priv, err := generate(context.Background(), splitHosts(host), &o)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyBlock, err := marshalPrivateKey(priv, &o)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cert.Raw, keyBlock.Bytes, nil
+}
+
+// splitHosts splits the comma-separated hostnames and IPs, trimming
+// surrounding whitespace and skipping empty entries
+func splitHosts(host string) []string {
+	return trimHosts(strings.Split(host, ","))
+}
+
+// trimHosts trims the surrounding whitespace of the hosts and drops the blank ones
+func trimHosts(hosts []string) []string {
+	var trimmed []string
+	for _, h := range hosts {
+		if h = strings.TrimSpace(h); len(h) > 0 {
+			trimmed = append(trimmed, h)
+		}
+	}
+
+	return trimmed
+}
+
+func generate(ctx context.Context, hosts []string, o *options) (*x509.Certificate, any, error) {
+	// a CA identified by its subject common name does not need any SANs
+	if len(hosts) == 0 && !(o.isCA && len(o.subject.CommonName) > 0) {
+		return nil, nil, ErrMissingHost
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	priv, err := privateKey(o)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template, err := newTemplate(publicKey(priv), o)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rangeHosts, err := expandIPRanges(o.ipRanges)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	allHosts := append(append([]string{}, hosts...), rangeHosts...)
+	if o.reverseDNS {
+		allHosts = append(allHosts, reverseDNSNames(ctx, allHosts)...)
+	}
+
+	template.DNSNames, template.IPAddresses, err = parseHosts(allHosts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template.EmailAddresses = o.emails
+	template.URIs, err = parseURIs(o.uris)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	parentCert := template
+	var parentKey any = priv
+	if o.signerCert != nil {
+		parentCert, parentKey = o.signerCert, o.signerKey
+		template.AuthorityKeyId = parentCert.SubjectKeyId
+	} else if len(o.parentCert) > 0 {
+		parentCert, parentKey, err = loadSigner(o.parentCert, o.parentKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		template.AuthorityKeyId = parentCert.SubjectKeyId
+	} else if o.issuer != nil {
+		// x509.CreateCertificate takes the issuer from the parent's subject
+		template.Issuer = *o.issuer
+		issuer := *template
+		issuer.Subject = *o.issuer
+		parentCert = &issuer
+	}
+
+	if err = checkSignatureAlgorithm(o.signatureAlgorithm, parentKey); err != nil {
+		return nil, nil, err
+	}
+
+	if o.strict {
+		if err = checkStrict(template, publicKey(priv), publicKey(parentKey), o); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if template.SerialNumber == nil {
+		if template.SerialNumber, err = nextSerialNumber(o.serialSource); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	derBytes, err := x509.CreateCertificate(o.rand, template, parentCert, publicKey(priv), parentKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create certificate: %w", err)
+	}
+
+	cert, err := x509.ParseCertificate(derBytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse created certificate: %w", err)
+	}
+
+	return cert, priv, nil
+}
+
+// loadSigner loads the certificate and matching private key of the signer, which must be a CA
+func loadSigner(certPath, keyPath string) (*x509.Certificate, any, error) {
+	cert, err := ParsePemCertFile(certPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !cert.IsCA {
+		return nil, nil, fmt.Errorf("parent certificate %s is not a CA", certPath)
+	}
+
+	if cert.KeyUsage != 0 && cert.KeyUsage&x509.KeyUsageCertSign == 0 {
+		return nil, nil, fmt.Errorf("parent certificate %s is not allowed to sign certificates (missing CertSign key usage)", certPath)
+	}
+
+	key, err := ParsePemKeyFile(keyPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !matchesPublicKey(key, cert.PublicKey) {
+		return nil, nil, fmt.Errorf("parent private key %s does not match parent certificate %s", keyPath, certPath)
+	}
+
+	return cert, key, nil
+}
+
+// newTemplate builds the certificate template for the given subject public key
+func newTemplate(pub any, o *options) (*x509.Certificate, error) {
+	// ECDSA, ED25519 and RSA subject keys should have the DigitalSignature
+	// KeyUsage bits set in the x509.Certificate template
+	keyUsage := x509.KeyUsageDigitalSignature
+	// Only RSA subject keys should have the KeyEncipherment KeyUsage bits set. In
+	// the context of TLS this KeyUsage is particular to RSA key exchange and
+	// authentication.
+	if _, isRSA := pub.(*rsa.PublicKey); isRSA {
+		keyUsage |= x509.KeyUsageKeyEncipherment
+	}
+
+	if o.keyUsage != 0 {
+		keyUsage = o.keyUsage
+	}
+
+	var notBefore time.Time
+	switch {
+	case !o.notBefore.IsZero():
+		notBefore = o.notBefore
+	case len(o.validFrom) == 0:
+		notBefore = time.Now()
+	default:
+		var err error
+		notBefore, err = parseStartDate(o.validFrom)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	notAfter := notBefore.Add(o.validFor)
+	if !o.notAfter.IsZero() {
+		if !o.notAfter.After(notBefore) {
+			return nil, fmt.Errorf("expiry date %v is not after creation date %v", o.notAfter, notBefore)
+		}
+		notAfter = o.notAfter
+	}
+
+	if o.maxValidity > 0 && notAfter.Sub(notBefore) > o.maxValidity {
+		return nil, fmt.Errorf("validity %v exceeds the maximum of %v", notAfter.Sub(notBefore), o.maxValidity)
+	}
+
+	// backdate after computing the expiry so the skew does not shorten the validity
+	notBefore = notBefore.Add(-o.clockSkew)
+
+	serialNumber, err := newSerialNumber(o)
+	if err != nil {
+		return nil, err
+	}
+
+	subjectKeyID, err := subjectKeyID(pub)
+	if err != nil {
+		return nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		SubjectKeyId: subjectKeyID,
+		Subject:      o.subject,
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+
+		KeyUsage:              keyUsage,
+		ExtKeyUsage:           o.extKeyUsage,
+		BasicConstraintsValid: !o.noBasicConstraints || o.isCA,
+		SignatureAlgorithm:    o.signatureAlgorithm,
+	}
+
+	if o.isCA {
+		template.IsCA = true
+		template.KeyUsage |= x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	}
+
+	if o.pathLen >= 0 {
+		if !o.isCA {
+			return nil, fmt.Errorf("path length constraint requires a CA certificate")
+		}
+		template.MaxPathLen = o.pathLen
+		template.MaxPathLenZero = o.pathLen == 0
+	}
+
+	if len(o.permittedDNS) > 0 || len(o.excludedDNS) > 0 {
+		if !o.isCA {
+			return nil, fmt.Errorf("name constraints require a CA certificate")
+		}
+		template.PermittedDNSDomainsCritical = true
+		template.PermittedDNSDomains = o.permittedDNS
+		template.ExcludedDNSDomains = o.excludedDNS
+	}
+
+	if len(o.policyOIDs) > 0 {
+		ext, err := certificatePolicies(o.policyOIDs)
+		if err != nil {
+			return nil, err
+		}
+		template.ExtraExtensions = append(template.ExtraExtensions, ext)
+	}
+
+	if o.criticalExtKeyUsage {
+		if len(o.extKeyUsage) == 0 {
+			return nil, fmt.Errorf("critical extended key usage requires at least one extended key usage")
+		}
+
+		ext, err := criticalExtKeyUsage(o.extKeyUsage)
+		if err != nil {
+			return nil, err
+		}
+		// an extra extension replaces the one generated from template.ExtKeyUsage
+		template.ExtraExtensions = append(template.ExtraExtensions, ext)
+	}
+
+	template.ExtraExtensions = append(template.ExtraExtensions, o.extraExtensions...)
+
+	return template, nil
+}
+
+// subjectKeyID computes the subject key identifier as the SHA-1 hash of the
+// public key bits (RFC 5280 section 4.2.1.2, method 1)
+func subjectKeyID(pub any) ([]byte, error) {
+	spki, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal public key: %w", err)
+	}
+
+	var info struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if _, err = asn1.Unmarshal(spki, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+
+	sum := sha1.Sum(info.PublicKey.Bytes)
+
+	return sum[:], nil
+}
+
+// parseHosts splits the hosts into DNS names and IP addresses, lowercasing
+// DNS names and dropping duplicates
+func parseHosts(hosts []string) ([]string, []net.IP, error) {
+	var dnsNames []string
+	var ips []net.IP
+	seen := make(map[string]bool)
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			if !containsIP(ips, ip) {
+				ips = append(ips, ip)
+			}
+			continue
+		}
+
+		name := strings.ToLower(h)
+		if err := checkWildcard(name); err != nil {
+			return nil, nil, err
+		}
+
+		if !seen[name] {
+			seen[name] = true
+			dnsNames = append(dnsNames, name)
+		}
+	}
+
+	return dnsNames, ips, nil
+}
+
+// maxIPRangeSize is the maximum number of addresses a CIDR given by WithIPRange may expand to
+const maxIPRangeSize = 256
+
+// expandIPRanges expands the CIDRs into their individual IP addresses.
+// X.509 has no IP range SAN, so every address is listed separately.
+func expandIPRanges(cidrs []string) ([]string, error) {
+	var hosts []string
+	for _, cidr := range cidrs {
+		ip, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse IP range %q: %w", cidr, err)
+		}
+
+		if !ip.Equal(ipNet.IP) {
+			return nil, fmt.Errorf("IP range %q is not a network address, did you mean %s", cidr, ipNet)
+		}
+
+		ones, bits := ipNet.Mask.Size()
+		if bits-ones > 8 || 1<<(bits-ones) > maxIPRangeSize {
+			return nil, fmt.Errorf("IP range %q is too large, at most %d addresses are allowed", cidr, maxIPRangeSize)
+		}
+
+		for ip := ipNet.IP; ipNet.Contains(ip); ip = nextIP(ip) {
+			hosts = append(hosts, ip.String())
+		}
+	}
+
+	return hosts, nil
+}
+
+// reverseDNSTimeout bounds all reverse DNS lookups of WithReverseDNS together, replaced in tests
+var reverseDNSTimeout = 5 * time.Second
+
+// reverseDNSConcurrency is the number of concurrent reverse DNS lookups
+const reverseDNSConcurrency = 16
+
+// lookupAddr performs the reverse DNS lookup, replaced in tests
+var lookupAddr = net.DefaultResolver.LookupAddr
+
+// reverseDNSNames returns the PTR names of the IP hosts in the order of the hosts, IPs
+// failing the lookup or not answered before the overall timeout are skipped
+func reverseDNSNames(ctx context.Context, hosts []string) []string {
+	var ips []string
+	for _, h := range hosts {
+		if net.ParseIP(h) != nil {
+			ips = append(ips, h)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, reverseDNSTimeout)
+	defer cancel()
+
+	results := make([][]string, len(ips))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < reverseDNSConcurrency && w < len(ips); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if ptrs, err := lookupAddr(ctx, ips[i]); err == nil {
+					results[i] = ptrs
+				}
+			}
+		}()
 	}
 
-	o := initOptions()
-	for _, opt := range opts {
-		opt(&o)
+feed:
+	for i := range ips {
+		select {
+		case next <- i:
+		case <-ctx.Done():
+			break feed
+		}
 	}
+	close(next)
+	wg.Wait()
 
-	var priv any
-	var err error
-	switch o.ecdsaCurve {
-	case "":
-		if o.ed25519Key {
-			_, priv, err = ed25519.GenerateKey(rand.Reader)
-		} else {
-			priv, err = rsa.GenerateKey(rand.Reader, o.rsaBits)
+	var names []string
+	for _, ptrs := range results {
+		for _, ptr := range ptrs {
+			if name := strings.TrimSuffix(ptr, "."); len(name) > 0 {
+				names = append(names, name)
+			}
 		}
-	case CurveP224:
-		priv, err = ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
-	case CurveP256:
-		priv, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
-	case CurveP384:
-		priv, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
-	case CurveP521:
-		priv, err = ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
-	default:
-		return fmt.Errorf("unrecognized elliptic curve: %q", o.ecdsaCurve)
 	}
 
-	if err != nil {
-		return fmt.Errorf("failed to generate private key: %v", err)
+	return names
+}
+
+// nextIP returns the IP address following ip
+func nextIP(ip net.IP) net.IP {
+	next := append(net.IP{}, ip...)
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			break
+		}
 	}
 
-	// ECDSA, ED25519 and RSA subject keys should have the DigitalSignature
-	// KeyUsage bits set in the x509.Certificate template
-	keyUsage := x509.KeyUsageDigitalSignature
-	// Only RSA subject keys should have the KeyEncipherment KeyUsage bits set. In
-	// the context of TLS this KeyUsage is particular to RSA key exchange and
-	// authentication.
-	if _, isRSA := priv.(*rsa.PrivateKey); isRSA {
-		keyUsage |= x509.KeyUsageKeyEncipherment
+	return next
+}
+
+// containsIP reports whether ip is in ips
+func containsIP(ips []net.IP, ip net.IP) bool {
+	for _, i := range ips {
+		if i.Equal(ip) {
+			return true
+		}
 	}
 
-	var notBefore time.Time
-	if len(o.validFrom) == 0 {
-		notBefore = time.Now()
-	} else {
-		notBefore, err = time.Parse("Jan 2 15:04:05 2006", o.validFrom)
+	return false
+}
+
+// checkWildcard returns an error if the DNS name contains a wildcard other than
+// a single leftmost '*' label followed by at least two labels (RFC 6125 section 6.4.3)
+func checkWildcard(name string) error {
+	if !strings.Contains(name, "*") {
+		return nil
+	}
+
+	rest := strings.TrimPrefix(name, "*.")
+	if rest == name || strings.Contains(rest, "*") {
+		return fmt.Errorf("invalid wildcard DNS name %q: only a single leftmost '*' label is allowed", name)
+	}
+
+	if labels := strings.Split(rest, "."); len(labels) < 2 || labels[0] == "" || labels[len(labels)-1] == "" {
+		return fmt.Errorf("invalid wildcard DNS name %q: wildcard must be followed by at least two labels", name)
+	}
+
+	return nil
+}
+
+// parseURIs parses the absolute URIs given by WithURISAN
+func parseURIs(uris []string) ([]*url.URL, error) {
+	var parsed []*url.URL
+	for _, u := range uris {
+		uri, err := url.Parse(u)
 		if err != nil {
-			return fmt.Errorf("failed to parse creation date: %v", err)
+			return nil, fmt.Errorf("failed to parse URI %q: %w", u, err)
+		}
+		if !uri.IsAbs() {
+			return nil, fmt.Errorf("URI %q must be absolute", u)
 		}
+		parsed = append(parsed, uri)
+	}
+
+	return parsed, nil
+}
+
+// startDateLayouts are the accepted layouts of WithStartDate
+var startDateLayouts = []string{"Jan 2 15:04:05 2006", time.RFC3339}
+
+// parseStartDate parses the creation date in any of the accepted layouts
+func parseStartDate(value string) (time.Time, error) {
+	for _, layout := range startDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("failed to parse creation date %q: accepted formats are %q", value, startDateLayouts)
+}
+
+// newSerialNumber returns the serial number given by WithSerialNumber, the next one of
+// WithSerialSource or a random 128-bit one
+func newSerialNumber(o *options) (*big.Int, error) {
+	if o.customSerial {
+		if o.serialNumber == nil || o.serialNumber.Sign() <= 0 {
+			return nil, fmt.Errorf("serial number must be a positive integer")
+		}
+
+		return o.serialNumber, nil
+	}
+
+	// drawn by nextSerialNumber once the template passed all checks, so a failure
+	// does not use up a serial number of the source
+	if o.serialSource != nil {
+		return nil, nil
 	}
 
-	notAfter := notBefore.Add(o.validFor)
 	serialNumberLimit := new(big.Int).Lsh(big.NewInt(1), 128)
 
-	serialNumber, err := rand.Int(rand.Reader, serialNumberLimit)
+	serialNumber, err := rand.Int(o.rand, serialNumberLimit)
 	if err != nil {
-		return fmt.Errorf("failed to generate serial number: %v", err)
+		return nil, fmt.Errorf("failed to generate serial number: %w", err)
 	}
 
-	var parentCert *x509.Certificate
-	template := x509.Certificate{
-		SerialNumber: serialNumber,
-		Subject: pkix.Name{
-			Organization: []string{"Acme Co"},
-		},
-		NotBefore: notBefore,
-		NotAfter:  notAfter,
+	return serialNumber, nil
+}
 
-		KeyUsage:              keyUsage,
-		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
-		BasicConstraintsValid: true,
+// nextSerialNumber draws the next serial number of the WithSerialSource source
+func nextSerialNumber(source SerialSource) (*big.Int, error) {
+	serial := source.Next()
+	if serial != nil && serial.Sign() > 0 {
+		return serial, nil
 	}
 
-	hosts := strings.Split(host, ",")
-	for _, h := range hosts {
-		if ip := net.ParseIP(h); ip != nil {
-			template.IPAddresses = append(template.IPAddresses, ip)
-		} else {
-			template.DNSNames = append(template.DNSNames, h)
+	if s, ok := source.(interface{ Err() error }); ok {
+		if err := s.Err(); err != nil {
+			return nil, fmt.Errorf("failed to get serial number: %w", err)
 		}
 	}
 
-	if o.isCA {
-		template.IsCA = true
-		template.KeyUsage |= x509.KeyUsageCertSign
+	return nil, fmt.Errorf("serial number source returned no positive serial number")
+}
+
+// privateKey loads the key given by WithExistingKey or generates a new one
+func privateKey(o *options) (any, error) {
+	var priv any
+	var err error
+	if len(o.existingKey) > 0 {
+		priv, err = ParsePemKeyFile(o.existingKey)
+	} else {
+		priv, err = generateKey(o)
 	}
 
-	parentCert = &template
-	parentKey := priv
-	if len(o.parentCert) > 0 {
-		parentCert, err = ParsePemCertFile(o.parentCert)
-		if err != nil {
-			return err
-		}
-		parentKey, err = ParsePemKeyFile(o.parentKey)
-		if err != nil {
-			return err
+	if err != nil {
+		return nil, err
+	}
+
+	if publicKey(priv) == nil {
+		return nil, fmt.Errorf("unsupported private key type: %T", priv)
+	}
+
+	return priv, nil
+}
+
+// generateKey generates a new private key of the type selected by the options
+func generateKey(o *options) (any, error) {
+	var priv any
+	var err error
+	switch o.ecdsaCurve {
+	case "":
+		if o.ed25519Key {
+			_, priv, err = ed25519.GenerateKey(o.rand)
+		} else {
+			if o.rsaBits < minRSABits && !o.insecureRSA {
+				return nil, fmt.Errorf("RSA key size %d is below the minimum of %d bits, use WithInsecureRSABits to allow it", o.rsaBits, minRSABits)
+			}
+			priv, err = rsa.GenerateKey(o.rand, o.rsaBits)
 		}
+	case CurveP224:
+		priv, err = ecdsa.GenerateKey(elliptic.P224(), o.rand)
+	case CurveP256:
+		priv, err = ecdsa.GenerateKey(elliptic.P256(), o.rand)
+	case CurveP384:
+		priv, err = ecdsa.GenerateKey(elliptic.P384(), o.rand)
+	case CurveP521:
+		priv, err = ecdsa.GenerateKey(elliptic.P521(), o.rand)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnrecognizedCurve, o.ecdsaCurve)
 	}
 
-	derBytes, err := x509.CreateCertificate(rand.Reader, &template, parentCert, publicKey(priv), parentKey)
 	if err != nil {
-		return fmt.Errorf("failed to create certificate: %v", err)
+		return nil, fmt.Errorf("failed to generate private key: %w", err)
 	}
 
-	certOut, err := os.Create(fmt.Sprintf("%s/%s", dest, o.certFileName))
+	return priv, nil
+}
+
+// encodePEM marshals the certificate and private key into PEM blocks
+func encodePEM(cert *x509.Certificate, priv any, o *options) ([]byte, []byte, error) {
+	keyBlock, err := marshalPrivateKey(priv, o)
 	if err != nil {
-		return fmt.Errorf("failed to open cert.pem for writing: %v", err)
+		return nil, nil, err
 	}
 
-	if err := pem.Encode(certOut, &pem.Block{Type: "CERTIFICATE", Bytes: derBytes}); err != nil {
-		return fmt.Errorf("failed to write data to cert.pem: %v", err)
+	certPEM := pem.EncodeToMemory(certBlock(cert.Raw, o))
+	keyPEM := pem.EncodeToMemory(keyBlock)
+	if o.crlf {
+		certPEM, keyPEM = toCRLF(certPEM), toCRLF(keyPEM)
 	}
 
-	if err := certOut.Close(); err != nil {
-		return fmt.Errorf("error closing cert.pem: %v", err)
+	return certPEM, keyPEM, nil
+}
+
+// toCRLF converts the LF line endings of the pem data to CRLF
+func toCRLF(data []byte) []byte {
+	return bytes.ReplaceAll(data, []byte("\n"), []byte("\r\n"))
+}
+
+// certBlock builds the certificate pem block with the headers of WithPEMHeaders
+func certBlock(der []byte, o *options) *pem.Block {
+	return &pem.Block{Type: "CERTIFICATE", Headers: o.pemHeaders, Bytes: der}
+}
+
+// marshalPrivateKey marshals the private key into a PEM block in the format selected by the options
+func marshalPrivateKey(priv any, o *options) (*pem.Block, error) {
+	if o.pkcs1 {
+		rsaKey, ok := priv.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("PKCS#1 format is only supported for RSA keys")
+		}
+		if len(o.passphrase) > 0 {
+			return nil, fmt.Errorf("passphrase encryption is only supported for PKCS#8 keys")
+		}
+
+		return &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}, nil
 	}
 
-	keyOut, err := os.OpenFile(fmt.Sprintf("%s/%s", dest, o.keyFileName), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
-	if err != nil {
-		return fmt.Errorf("failed to open key.pem for writing: %v", err)
+	if o.sec1 {
+		ecKey, ok := priv.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("SEC1 format is only supported for ECDSA keys")
+		}
+		if len(o.passphrase) > 0 {
+			return nil, fmt.Errorf("passphrase encryption is only supported for PKCS#8 keys")
+		}
+
+		privBytes, err := x509.MarshalECPrivateKey(ecKey)
+		if err != nil {
+			return nil, fmt.Errorf("unable to marshal private key: %w", err)
+		}
+
+		return &pem.Block{Type: "EC PRIVATE KEY", Bytes: privBytes}, nil
 	}
 
 	privBytes, err := x509.MarshalPKCS8PrivateKey(priv)
 	if err != nil {
-		return fmt.Errorf("unable to marshal private key: %v", err)
+		return nil, fmt.Errorf("unable to marshal private key: %w", err)
 	}
 
-	if err = pem.Encode(keyOut, &pem.Block{Type: "PRIVATE KEY", Bytes: privBytes}); err != nil {
-		return fmt.Errorf("failed to write data to key.pem: %v", err)
-	}
+	if len(o.passphrase) > 0 {
+		encrypted, err := encryptPKCS8(privBytes, o.passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("unable to encrypt private key: %w", err)
+		}
 
-	if err = keyOut.Close(); err != nil {
-		return fmt.Errorf("error closing key.pem: %v", err)
+		return &pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: encrypted}, nil
 	}
 
-	return nil
+	return &pem.Block{Type: "PRIVATE KEY", Bytes: privBytes}, nil
 }
 
 // ParsePemCertFile parses the given pem certificate file
 func ParsePemCertFile(path string) (*x509.Certificate, error) {
-	der, err := os.ReadFile(path)
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %v", err)
+		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
 
-	block, _ := pem.Decode(der)
+	return ParsePemCert(data)
+}
+
+// ParsePemCert parses the given pem encoded certificate
+func ParsePemCert(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
 	if block == nil || block.Type != "CERTIFICATE" {
 		return nil, fmt.Errorf("failed to parse certificate PEM")
 	}
 
-	parentCert, err := x509.ParseCertificate(block.Bytes)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse DER data: %w", err)
+	}
+
+	return cert, nil
+}
+
+// ParsePemCertChainFile parses all the certificates in the given pem file (e.g. a full chain bundle)
+func ParsePemCertChainFile(path string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse DER data: %v", err)
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	return ParsePemCertChain(data)
+}
+
+// ParsePemCertChain parses all the pem encoded certificates in order, skipping other PEM blocks
+func ParsePemCertChain(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse DER data: %w", err)
+		}
+
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("failed to parse certificate PEM")
 	}
 
-	return parentCert, nil
+	return certs, nil
 }
 
 // Verify the certificate's signature
 func Verify(rootCertPath, certPath, dnsName string) error {
+	return VerifyChain(rootCertPath, certPath, dnsName)
+}
+
+// VerifyChain verifies the certificate's signature through the given intermediate
+// certificate files (leaf -> intermediates -> root)
+func VerifyChain(rootCertPath, certPath, dnsName string, intermediatePaths ...string) error {
+	_, err := verify(rootCertPath, certPath, intermediatePaths, x509.VerifyOptions{DNSName: dnsName})
+	return err
+}
+
+// VerifyChains is like VerifyChain but returns the verified chains, each starting
+// with the certificate and ending with the root
+func VerifyChains(rootCertPath, certPath, dnsName string, intermediatePaths ...string) ([][]*x509.Certificate, error) {
+	return verify(rootCertPath, certPath, intermediatePaths, x509.VerifyOptions{DNSName: dnsName})
+}
+
+// VerifyWithUsage verifies the certificate's signature and that it is valid for
+// any of the given extended key usages (e.g. x509.ExtKeyUsageClientAuth)
+func VerifyWithUsage(rootCertPath, certPath, dnsName string, usages []x509.ExtKeyUsage) error {
+	_, err := verify(rootCertPath, certPath, nil, x509.VerifyOptions{DNSName: dnsName, KeyUsages: usages})
+	return err
+}
+
+// VerifyWithOptions verifies the certificate's signature with the given verify options (e.g. WithVerifyTime)
+func VerifyWithOptions(rootCertPath, certPath, dnsName string, opts ...VerifyOption) error {
+	verifyOpts := x509.VerifyOptions{DNSName: dnsName}
+	for _, opt := range opts {
+		opt(&verifyOpts)
+	}
+
+	_, err := verify(rootCertPath, certPath, nil, verifyOpts)
+	return err
+}
+
+// VerifyBundle verifies the certificate's signature against a pem bundle holding roots and
+// intermediates, like a CA bundle of curl. Self-signed certificates of the bundle are trusted
+// as roots, the others are used as intermediates
+func VerifyBundle(bundlePath, certPath, dnsName string) error {
+	bundle, err := ParsePemCertChainFile(bundlePath)
+	if err != nil {
+		return err
+	}
+
 	roots := x509.NewCertPool()
-	rootCert, err := ParsePemCertFile(rootCertPath)
+	intermediates := x509.NewCertPool()
+	var hasRoot bool
+	for _, c := range bundle {
+		if isSelfSigned(c) {
+			roots.AddCert(c)
+			hasRoot = true
+		} else {
+			intermediates.AddCert(c)
+		}
+	}
+
+	if !hasRoot {
+		return fmt.Errorf("no self-signed root certificate in bundle %s", bundlePath)
+	}
+
+	_, err = verifyCert(certPath, x509.VerifyOptions{DNSName: dnsName, Roots: roots, Intermediates: intermediates})
+	return err
+}
+
+// VerifySystem verifies the certificate's signature against the system root store through
+// the given intermediate certificate files, e.g. for certificates fetched from real servers
+func VerifySystem(certPath, dnsName string, intermediatePaths ...string) error {
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		return fmt.Errorf("failed to load system root store: %w", err)
+	}
+
+	intermediates, err := certPool(intermediatePaths)
 	if err != nil {
 		return err
 	}
 
+	_, err = verifyCert(certPath, x509.VerifyOptions{DNSName: dnsName, Roots: roots, Intermediates: intermediates})
+
+	return err
+}
+
+// isSelfSigned reports whether the certificate is issued and signed by itself
+func isSelfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawIssuer, cert.RawSubject) &&
+		cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil
+}
+
+// verify verifies the certificate against the root and intermediate certificate files
+// with the given options and returns the verified chains, the Roots and Intermediates of opts are replaced
+func verify(rootCertPath, certPath string, intermediatePaths []string, opts x509.VerifyOptions) ([][]*x509.Certificate, error) {
+	roots := x509.NewCertPool()
+	rootCert, err := ParsePemCertFile(rootCertPath)
+	if err != nil {
+		return nil, err
+	}
+
 	roots.AddCert(rootCert)
 
+	intermediates, err := certPool(intermediatePaths)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.Roots = roots
+	opts.Intermediates = intermediates
+
+	return verifyCert(certPath, opts)
+}
+
+// certPool builds a pool of all certificates of the given pem files
+func certPool(paths []string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	for _, path := range paths {
+		certs, err := ParsePemCertChainFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, c := range certs {
+			pool.AddCert(c)
+		}
+	}
+
+	return pool, nil
+}
+
+// verifyCert verifies the pem certificate file with the given options and returns the verified chains
+func verifyCert(certPath string, opts x509.VerifyOptions) ([][]*x509.Certificate, error) {
 	cert, err := ParsePemCertFile(certPath)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	opts := x509.VerifyOptions{
-		DNSName: dnsName,
-		Roots:   roots,
+	chains, err := cert.Verify(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify certificate: %w", err)
 	}
 
-	if _, err := cert.Verify(opts); err != nil {
-		return fmt.Errorf("failed to verify certificate: %v", err)
+	return chains, nil
+}
+
+// MatchesHostname reports whether the certificate's SANs cover the given host name or IP,
+// including wildcard names. Unlike Verify the certificate's chain is not checked
+func MatchesHostname(cert *x509.Certificate, name string) bool {
+	return cert.VerifyHostname(name) == nil
+}
+
+// FileMatchesHostname reports whether the pem certificate file covers the given host name (see MatchesHostname)
+func FileMatchesHostname(path, name string) (bool, error) {
+	cert, err := ParsePemCertFile(path)
+	if err != nil {
+		return false, err
 	}
 
-	return nil
+	return MatchesHostname(cert, name), nil
 }
 
 // ParsePemKeyFile parses the given pem key file
 func ParsePemKeyFile(path string) (any, error) {
-	der, err := os.ReadFile(path)
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %v", err)
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	return ParsePemKey(data)
+}
+
+// ParsePemKey parses the given pem encoded key. PKCS#8 ("PRIVATE KEY"), PKCS#1 ("RSA PRIVATE KEY")
+// and SEC1 ("EC PRIVATE KEY") keys are supported, the format of the block type is tried first.
+// Leading "EC PARAMETERS" blocks written by openssl ecparam are skipped
+func ParsePemKey(data []byte) (any, error) {
+	block, rest := pem.Decode(data)
+	for block != nil && block.Type == "EC PARAMETERS" {
+		block, rest = pem.Decode(rest)
 	}
 
-	block, _ := pem.Decode(der)
-	if block == nil || block.Type != "PRIVATE KEY" {
+	if block == nil {
 		return nil, fmt.Errorf("failed to parse key PEM")
 	}
 
-	pkey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if block.Type == "ENCRYPTED PRIVATE KEY" || strings.Contains(block.Headers["Proc-Type"], "ENCRYPTED") {
+		return nil, ErrEncryptedKey
+	}
+
+	var parsers []func([]byte) (any, error)
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsers = []func([]byte) (any, error){x509.ParsePKCS8PrivateKey, parsePKCS1PrivateKey, parseECPrivateKey}
+	case "RSA PRIVATE KEY":
+		parsers = []func([]byte) (any, error){parsePKCS1PrivateKey, x509.ParsePKCS8PrivateKey, parseECPrivateKey}
+	case "EC PRIVATE KEY":
+		parsers = []func([]byte) (any, error){parseECPrivateKey, x509.ParsePKCS8PrivateKey, parsePKCS1PrivateKey}
+	default:
+		return nil, fmt.Errorf("failed to parse key PEM: unsupported block type %q", block.Type)
+	}
+
+	// keep the error of the format matching the block type, it is the most helpful one
+	var firstErr error
+	for _, parse := range parsers {
+		pkey, err := parse(block.Bytes)
+		if err == nil {
+			return pkey, nil
+		}
+
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return nil, fmt.Errorf("failed to parse DER data: %w", firstErr)
+}
+
+// parsePKCS1PrivateKey is x509.ParsePKCS1PrivateKey returning any
+func parsePKCS1PrivateKey(der []byte) (any, error) {
+	return x509.ParsePKCS1PrivateKey(der)
+}
+
+// parseECPrivateKey is x509.ParseECPrivateKey returning any
+func parseECPrivateKey(der []byte) (any, error) {
+	return x509.ParseECPrivateKey(der)
+}
+
+// ParseEncryptedPemKeyFile parses the given pem key file encrypted with WithKeyPassphrase
+// or any PBES2 encrypted PKCS#8 key using PBKDF2 and AES-CBC (e.g. by openssl)
+func ParseEncryptedPemKeyFile(path string, password []byte) (any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	return ParseEncryptedPemKey(data, password)
+}
+
+// ParseEncryptedPemKey parses the given pem encoded key encrypted with WithKeyPassphrase
+// or any PBES2 encrypted PKCS#8 key using PBKDF2 and AES-CBC (e.g. by openssl)
+func ParseEncryptedPemKey(data, password []byte) (any, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "ENCRYPTED PRIVATE KEY" {
+		return nil, fmt.Errorf("failed to parse encrypted key PEM")
+	}
+
+	decrypted, err := decryptPKCS8(block.Bytes, password)
+	if err != nil {
+		return nil, err
+	}
 
+	pkey, err := x509.ParsePKCS8PrivateKey(decrypted)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse DER data: %v", err)
+		return nil, fmt.Errorf("failed to parse DER data: %w", err)
 	}
 
 	return pkey, nil
 }
 
+// LoadKeyPair parses the pem certificate and private key files and checks that the
+// private key belongs to the certificate
+func LoadKeyPair(certPath, keyPath string) (*x509.Certificate, any, error) {
+	cert, err := ParsePemCertFile(certPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key, err := ParsePemKeyFile(keyPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !matchesPublicKey(key, cert.PublicKey) {
+		return nil, nil, fmt.Errorf("%w: %s and %s", ErrKeyMismatch, keyPath, certPath)
+	}
+
+	return cert, key, nil
+}
+
 func publicKey(priv any) any {
 	switch k := priv.(type) {
 	case *rsa.PrivateKey:
@@ -247,3 +1516,40 @@ func publicKey(priv any) any {
 		return nil
 	}
 }
+
+// checkSignatureAlgorithm returns an error if the signature algorithm can not be used with the signer key.
+// x509.UnknownSignatureAlgorithm lets x509 pick the default for the key.
+func checkSignatureAlgorithm(alg x509.SignatureAlgorithm, signer any) error {
+	if alg == x509.UnknownSignatureAlgorithm {
+		return nil
+	}
+
+	var compatible bool
+	switch signer.(type) {
+	case *rsa.PrivateKey:
+		switch alg {
+		case x509.MD5WithRSA, x509.SHA1WithRSA, x509.SHA256WithRSA, x509.SHA384WithRSA, x509.SHA512WithRSA,
+			x509.SHA256WithRSAPSS, x509.SHA384WithRSAPSS, x509.SHA512WithRSAPSS:
+			compatible = true
+		}
+	case *ecdsa.PrivateKey:
+		switch alg {
+		case x509.ECDSAWithSHA1, x509.ECDSAWithSHA256, x509.ECDSAWithSHA384, x509.ECDSAWithSHA512:
+			compatible = true
+		}
+	case ed25519.PrivateKey:
+		compatible = alg == x509.PureEd25519
+	}
+
+	if !compatible {
+		return fmt.Errorf("signature algorithm %v is not compatible with %T signer key", alg, signer)
+	}
+
+	return nil
+}
+
+// matchesPublicKey reports whether the private key belongs to the given public key
+func matchesPublicKey(priv, pub any) bool {
+	k, ok := publicKey(priv).(interface{ Equal(crypto.PublicKey) bool })
+	return ok && k.Equal(pub)
+}
@@ -1,7 +1,28 @@
 package gcert
 
 import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	mrand "math/rand"
+	"net"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -298,3 +319,3113 @@ func TestGenerateSingByParent(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateWithOrganization(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want []string
+	}{
+		{
+			name: "default organization",
+			opts: []Option{},
+			want: []string{"Acme Co"},
+		},
+		{
+			name: "single organization",
+			opts: []Option{WithOrganization("Example Inc")},
+			want: []string{"Example Inc"},
+		},
+		{
+			name: "multiple organizations",
+			opts: []Option{WithOrganization("Example Inc", "Example Labs")},
+			want: []string{"Example Inc", "Example Labs"},
+		},
+		{
+			name: "empty organization",
+			opts: []Option{WithOrganization()},
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			if err := Generate("test.example.com", dest, tt.opts...); err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+
+			cert, err := ParsePemCertFile(dest + "/cert.pem")
+			if err != nil {
+				t.Fatalf("ParsePemCertFile() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(cert.Subject.Organization, tt.want) {
+				t.Errorf("Subject.Organization = %v, want %v", cert.Subject.Organization, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateCert(t *testing.T) {
+	tests := []struct {
+		name    string
+		host    string
+		opts    []Option
+		wantKey any
+		wantErr bool
+	}{
+		{
+			name:    "with no options",
+			host:    "test.example.com",
+			wantKey: &rsa.PrivateKey{},
+		},
+		{
+			name:    "with P256",
+			host:    "test.example.com",
+			opts:    []Option{WithP256()},
+			wantKey: &ecdsa.PrivateKey{},
+		},
+		{
+			name:    "with ED25519",
+			host:    "test.example.com",
+			opts:    []Option{WithED25519()},
+			wantKey: ed25519.PrivateKey{},
+		},
+		{
+			name:    "with missing host",
+			host:    "",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, priv, err := GenerateCert(tt.host, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenerateCert() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if reflect.TypeOf(priv) != reflect.TypeOf(tt.wantKey) {
+				t.Errorf("GenerateCert() key type = %T, want %T", priv, tt.wantKey)
+			}
+
+			if err = cert.VerifyHostname(tt.host); err != nil {
+				t.Errorf("VerifyHostname() error = %v", err)
+			}
+
+			if err = cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature); err != nil {
+				t.Errorf("CheckSignature() error = %v", err)
+			}
+		})
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestGenerateTo(t *testing.T) {
+	var certOut, keyOut bytes.Buffer
+	if err := GenerateTo("test.example.com", &certOut, &keyOut); err != nil {
+		t.Fatalf("GenerateTo() error = %v", err)
+	}
+
+	block, _ := pem.Decode(certOut.Bytes())
+	if block == nil || block.Type != "CERTIFICATE" {
+		t.Fatalf("GenerateTo() certOut is not a certificate PEM")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("ParseCertificate() error = %v", err)
+	}
+
+	block, _ = pem.Decode(keyOut.Bytes())
+	if block == nil || block.Type != "PRIVATE KEY" {
+		t.Fatalf("GenerateTo() keyOut is not a private key PEM")
+	}
+
+	priv, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		t.Fatalf("ParsePKCS8PrivateKey() error = %v", err)
+	}
+
+	if !priv.(*rsa.PrivateKey).PublicKey.Equal(cert.PublicKey) {
+		t.Errorf("GenerateTo() private key does not match certificate")
+	}
+}
+
+func TestGenerateToPartialFailure(t *testing.T) {
+	var certOut bytes.Buffer
+	err := GenerateTo("test.example.com", &certOut, failingWriter{})
+	if err == nil {
+		t.Fatalf("GenerateTo() expected error")
+	}
+
+	if certOut.Len() == 0 {
+		t.Errorf("GenerateTo() expected certificate to be written before the key")
+	}
+
+	if !strings.Contains(err.Error(), "certificate was written") {
+		t.Errorf("GenerateTo() error = %v, want partial write error", err)
+	}
+
+	if err = GenerateTo("test.example.com", failingWriter{}, &bytes.Buffer{}); err == nil {
+		t.Errorf("GenerateTo() expected error for failing certificate writer")
+	}
+}
+
+func TestGenerateWithEmailSAN(t *testing.T) {
+	emails := []string{"admin@example.com", "ops@example.com"}
+
+	cert, _, err := GenerateCert("test.example.com", WithEmailSAN(emails...))
+	if err != nil {
+		t.Fatalf("GenerateCert() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(cert.EmailAddresses, emails) {
+		t.Errorf("EmailAddresses = %v, want %v", cert.EmailAddresses, emails)
+	}
+
+	if !reflect.DeepEqual(cert.DNSNames, []string{"test.example.com"}) {
+		t.Errorf("DNSNames = %v, want [test.example.com]", cert.DNSNames)
+	}
+}
+
+func TestGenerateWithURISAN(t *testing.T) {
+	tests := []struct {
+		name    string
+		uris    []string
+		wantErr bool
+	}{
+		{
+			name: "with spiffe id",
+			uris: []string{"spiffe://trust-domain/workload"},
+		},
+		{
+			name: "with multiple uris",
+			uris: []string{"spiffe://trust-domain/workload", "https://example.com/id"},
+		},
+		{
+			name:    "with malformed uri",
+			uris:    []string{"spiffe://trust-domain/%zz"},
+			wantErr: true,
+		},
+		{
+			name:    "with relative uri",
+			uris:    []string{"trust-domain/workload"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, _, err := GenerateCert("test.example.com", WithURISAN(tt.uris...))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenerateCert() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			var got []string
+			for _, u := range cert.URIs {
+				got = append(got, u.String())
+			}
+
+			if !reflect.DeepEqual(got, tt.uris) {
+				t.Errorf("URIs = %v, want %v", got, tt.uris)
+			}
+		})
+	}
+}
+
+func TestGenerateWithExtKeyUsage(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          []Option
+		wantServerErr bool
+		wantClientErr bool
+	}{
+		{
+			name:          "with no options",
+			opts:          []Option{},
+			wantClientErr: true,
+		},
+		{
+			name: "with ClientAuth",
+			opts: []Option{WithClientAuth()},
+		},
+		{
+			name:          "with ExtKeyUsage client auth only",
+			opts:          []Option{WithExtKeyUsage(x509.ExtKeyUsageClientAuth)},
+			wantServerErr: true,
+		},
+		{
+			name: "with ExtKeyUsage server and client auth",
+			opts: []Option{WithExtKeyUsage(x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, _, err := GenerateCert("test.example.com", tt.opts...)
+			if err != nil {
+				t.Fatalf("GenerateCert() error = %v", err)
+			}
+
+			roots := x509.NewCertPool()
+			roots.AddCert(cert)
+
+			_, err = cert.Verify(x509.VerifyOptions{
+				DNSName:   "test.example.com",
+				Roots:     roots,
+				KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+			})
+			if (err != nil) != tt.wantServerErr {
+				t.Errorf("Verify() server auth error = %v, wantErr %v", err, tt.wantServerErr)
+			}
+
+			_, err = cert.Verify(x509.VerifyOptions{
+				Roots:     roots,
+				KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+			})
+			if (err != nil) != tt.wantClientErr {
+				t.Errorf("Verify() client auth error = %v, wantErr %v", err, tt.wantClientErr)
+			}
+		})
+	}
+}
+
+func TestGenerateWithKeyUsage(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want x509.KeyUsage
+	}{
+		{
+			name: "default RSA key usage",
+			opts: []Option{},
+			want: x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		},
+		{
+			name: "default ECDSA key usage",
+			opts: []Option{WithP256()},
+			want: x509.KeyUsageDigitalSignature,
+		},
+		{
+			name: "with KeyUsage",
+			opts: []Option{WithKeyUsage(x509.KeyUsageDigitalSignature | x509.KeyUsageKeyAgreement)},
+			want: x509.KeyUsageDigitalSignature | x509.KeyUsageKeyAgreement,
+		},
+		{
+			name: "with KeyUsage stripping KeyEncipherment",
+			opts: []Option{WithKeyUsage(x509.KeyUsageDigitalSignature)},
+			want: x509.KeyUsageDigitalSignature,
+		},
+		{
+			name: "with KeyUsage and CA",
+			opts: []Option{WithKeyUsage(x509.KeyUsageDigitalSignature), WithCA()},
+			want: x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, _, err := GenerateCert("test.example.com", tt.opts...)
+			if err != nil {
+				t.Fatalf("GenerateCert() error = %v", err)
+			}
+
+			if cert.KeyUsage != tt.want {
+				t.Errorf("KeyUsage = %v, want %v", cert.KeyUsage, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateWithPKCS1(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr bool
+	}{
+		{
+			name: "with RSA key",
+			opts: []Option{WithPKCS1()},
+		},
+		{
+			name: "with RSA 4096 key",
+			opts: []Option{WithPKCS1(), WithRSABits(4096)},
+		},
+		{
+			name:    "with ECDSA key",
+			opts:    []Option{WithPKCS1(), WithP256()},
+			wantErr: true,
+		},
+		{
+			name:    "with passphrase",
+			opts:    []Option{WithPKCS1(), WithKeyPassphrase([]byte("secret"))},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			if err := Generate("test.example.com", dest, tt.opts...); (err != nil) != tt.wantErr {
+				t.Fatalf("Generate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			data, err := os.ReadFile(dest + "/key.pem")
+			if err != nil {
+				t.Fatalf("ReadFile() error = %v", err)
+			}
+
+			if block, _ := pem.Decode(data); block == nil || block.Type != "RSA PRIVATE KEY" {
+				t.Fatalf("key.pem is not a PKCS#1 PEM block")
+			}
+
+			cert, err := ParsePemCertFile(dest + "/cert.pem")
+			if err != nil {
+				t.Fatalf("ParsePemCertFile() error = %v", err)
+			}
+
+			priv, err := ParsePemKeyFile(dest + "/key.pem")
+			if err != nil {
+				t.Fatalf("ParsePemKeyFile() error = %v", err)
+			}
+
+			rsaKey, ok := priv.(*rsa.PrivateKey)
+			if !ok {
+				t.Fatalf("ParsePemKeyFile() key type = %T, want *rsa.PrivateKey", priv)
+			}
+
+			if !rsaKey.PublicKey.Equal(cert.PublicKey) {
+				t.Errorf("ParsePemKeyFile() key does not match certificate")
+			}
+		})
+	}
+}
+
+func TestGenerateWithExistingKey(t *testing.T) {
+	dest := t.TempDir()
+	if err := Generate("test.example.com", dest, WithP256(), WithCertFileName("first.pem")); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	err := Generate("other.example.com", dest, WithExistingKey(dest+"/key.pem"), WithED25519(), WithCertFileName("second.pem"), WithKeyFileName("second_key.pem"))
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	first, err := ParsePemCertFile(dest + "/first.pem")
+	if err != nil {
+		t.Fatalf("ParsePemCertFile() error = %v", err)
+	}
+
+	second, err := ParsePemCertFile(dest + "/second.pem")
+	if err != nil {
+		t.Fatalf("ParsePemCertFile() error = %v", err)
+	}
+
+	if !first.PublicKey.(*ecdsa.PublicKey).Equal(second.PublicKey) {
+		t.Errorf("certificates generated from the same key have different public keys")
+	}
+
+	if err = Generate("test.example.com", dest, WithExistingKey(dest+"/missing.pem")); err == nil {
+		t.Errorf("Generate() expected error for missing key file")
+	}
+}
+
+func TestGenerateWithSerialNumber(t *testing.T) {
+	tests := []struct {
+		name    string
+		serial  *big.Int
+		wantErr bool
+	}{
+		{
+			name:   "with small serial",
+			serial: big.NewInt(1),
+		},
+		{
+			name:   "with large serial",
+			serial: new(big.Int).Lsh(big.NewInt(1), 150),
+		},
+		{
+			name:    "with zero serial",
+			serial:  big.NewInt(0),
+			wantErr: true,
+		},
+		{
+			name:    "with negative serial",
+			serial:  big.NewInt(-42),
+			wantErr: true,
+		},
+		{
+			name:    "with nil serial",
+			serial:  nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, _, err := GenerateCert("test.example.com", WithP256(), WithSerialNumber(tt.serial))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenerateCert() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if cert.SerialNumber.Cmp(tt.serial) != 0 {
+				t.Errorf("SerialNumber = %v, want %v", cert.SerialNumber, tt.serial)
+			}
+		})
+	}
+}
+
+func TestParsePem(t *testing.T) {
+	var certOut, keyOut bytes.Buffer
+	if err := GenerateTo("test.example.com", &certOut, &keyOut, WithP256()); err != nil {
+		t.Fatalf("GenerateTo() error = %v", err)
+	}
+
+	cert, err := ParsePemCert(certOut.Bytes())
+	if err != nil {
+		t.Fatalf("ParsePemCert() error = %v", err)
+	}
+
+	priv, err := ParsePemKey(keyOut.Bytes())
+	if err != nil {
+		t.Fatalf("ParsePemKey() error = %v", err)
+	}
+
+	if !priv.(*ecdsa.PrivateKey).PublicKey.Equal(cert.PublicKey) {
+		t.Errorf("ParsePemKey() key does not match certificate")
+	}
+
+	if _, err = ParsePemCert(keyOut.Bytes()); err == nil {
+		t.Errorf("ParsePemCert() expected error for key PEM")
+	}
+
+	if _, err = ParsePemKey(certOut.Bytes()); err == nil {
+		t.Errorf("ParsePemKey() expected error for certificate PEM")
+	}
+
+	if _, err = ParsePemCert([]byte("not a pem")); err == nil {
+		t.Errorf("ParsePemCert() expected error for invalid data")
+	}
+
+	if _, err = ParsePemKey(nil); err == nil {
+		t.Errorf("ParsePemKey() expected error for empty data")
+	}
+}
+
+func TestParsePemCertChainFile(t *testing.T) {
+	dest := t.TempDir()
+	if err := Generate("cadomain.cert", dest, WithCA(), WithP256(), WithCertFileName("ca_cert.pem"), WithKeyFileName("ca_key.pem")); err != nil {
+		t.Fatalf("Generate() CA error = %v", err)
+	}
+
+	if err := Generate("test.example.com", dest, WithP256(), WithSignByParent(dest+"/ca_cert.pem", dest+"/ca_key.pem")); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	var bundle []byte
+	for _, name := range []string{"cert.pem", "key.pem", "ca_cert.pem"} {
+		data, err := os.ReadFile(dest + "/" + name)
+		if err != nil {
+			t.Fatalf("ReadFile() error = %v", err)
+		}
+		bundle = append(bundle, data...)
+	}
+
+	if err := os.WriteFile(dest+"/bundle.pem", bundle, 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	certs, err := ParsePemCertChainFile(dest + "/bundle.pem")
+	if err != nil {
+		t.Fatalf("ParsePemCertChainFile() error = %v", err)
+	}
+
+	if len(certs) != 2 {
+		t.Fatalf("ParsePemCertChainFile() returned %d certificates, want 2", len(certs))
+	}
+
+	if certs[0].IsCA || certs[0].DNSNames[0] != "test.example.com" {
+		t.Errorf("ParsePemCertChainFile() first certificate is not the leaf")
+	}
+
+	if !certs[1].IsCA {
+		t.Errorf("ParsePemCertChainFile() second certificate is not the CA")
+	}
+
+	if _, err = ParsePemCertChainFile(dest + "/key.pem"); err == nil {
+		t.Errorf("ParsePemCertChainFile() expected error for file without certificates")
+	}
+}
+
+func TestVerifyChain(t *testing.T) {
+	dest := t.TempDir()
+	if err := Generate("root.cert", dest, WithCA(), WithP256(), WithCertFileName("root_cert.pem"), WithKeyFileName("root_key.pem")); err != nil {
+		t.Fatalf("Generate() root error = %v", err)
+	}
+
+	err := Generate("intermediate.cert", dest, WithCA(), WithP256(), WithCertFileName("int_cert.pem"), WithKeyFileName("int_key.pem"),
+		WithSignByParent(dest+"/root_cert.pem", dest+"/root_key.pem"))
+	if err != nil {
+		t.Fatalf("Generate() intermediate error = %v", err)
+	}
+
+	if err = Generate("test.example.com", dest, WithP256(), WithSignByParent(dest+"/int_cert.pem", dest+"/int_key.pem")); err != nil {
+		t.Fatalf("Generate() leaf error = %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		intermediates []string
+		wantErr       bool
+	}{
+		{
+			name:    "without intermediate",
+			wantErr: true,
+		},
+		{
+			name:          "with intermediate",
+			intermediates: []string{dest + "/int_cert.pem"},
+		},
+		{
+			name:          "with missing intermediate file",
+			intermediates: []string{dest + "/missing.pem"},
+			wantErr:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyChain(dest+"/root_cert.pem", dest+"/cert.pem", "test.example.com", tt.intermediates...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("VerifyChain() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err = Verify(dest+"/root_cert.pem", dest+"/cert.pem", "test.example.com"); err == nil {
+		t.Errorf("Verify() expected error without intermediate")
+	}
+}
+
+func TestGenerateWithPathLen(t *testing.T) {
+	tests := []struct {
+		name           string
+		opts           []Option
+		wantMaxPathLen int
+		wantZero       bool
+		wantErr        bool
+	}{
+		{
+			name:           "with CA only",
+			opts:           []Option{WithCA()},
+			wantMaxPathLen: -1,
+		},
+		{
+			name:           "with PathLen 0",
+			opts:           []Option{WithCA(), WithPathLen(0)},
+			wantMaxPathLen: 0,
+			wantZero:       true,
+		},
+		{
+			name:           "with PathLen 2",
+			opts:           []Option{WithCA(), WithPathLen(2)},
+			wantMaxPathLen: 2,
+		},
+		{
+			name:    "with PathLen without CA",
+			opts:    []Option{WithPathLen(1)},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, _, err := GenerateCert("cadomain.cert", append(tt.opts, WithP256())...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenerateCert() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if cert.MaxPathLen != tt.wantMaxPathLen {
+				t.Errorf("MaxPathLen = %v, want %v", cert.MaxPathLen, tt.wantMaxPathLen)
+			}
+
+			if cert.MaxPathLenZero != tt.wantZero {
+				t.Errorf("MaxPathLenZero = %v, want %v", cert.MaxPathLenZero, tt.wantZero)
+			}
+		})
+	}
+}
+
+func TestGenerateWithNameConstraints(t *testing.T) {
+	tests := []struct {
+		name          string
+		caOpts        []Option
+		host          string
+		wantErr       bool
+		wantVerifyErr bool
+	}{
+		{
+			name:   "with permitted domain",
+			caOpts: []Option{WithPermittedDNSDomains("example.com")},
+			host:   "test.example.com",
+		},
+		{
+			name:          "outside permitted domain",
+			caOpts:        []Option{WithPermittedDNSDomains("example.com")},
+			host:          "test.other.com",
+			wantVerifyErr: true,
+		},
+		{
+			name:          "with excluded domain",
+			caOpts:        []Option{WithExcludedDNSDomains("internal.example.com")},
+			host:          "db.internal.example.com",
+			wantVerifyErr: true,
+		},
+		{
+			name:   "outside excluded domain",
+			caOpts: []Option{WithExcludedDNSDomains("internal.example.com")},
+			host:   "www.example.com",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			caOpts := append([]Option{WithCA(), WithP256(), WithCertFileName("ca_cert.pem"), WithKeyFileName("ca_key.pem")}, tt.caOpts...)
+			if err := Generate("cadomain.cert", dest, caOpts...); err != nil {
+				t.Fatalf("Generate() CA error = %v", err)
+			}
+
+			if err := Generate(tt.host, dest, WithP256(), WithSignByParent(dest+"/ca_cert.pem", dest+"/ca_key.pem")); err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+
+			ca, err := ParsePemCertFile(dest + "/ca_cert.pem")
+			if err != nil {
+				t.Fatalf("ParsePemCertFile() error = %v", err)
+			}
+
+			if !ca.PermittedDNSDomainsCritical {
+				t.Errorf("PermittedDNSDomainsCritical = false, want true")
+			}
+
+			if err = Verify(dest+"/ca_cert.pem", dest+"/cert.pem", tt.host); (err != nil) != tt.wantVerifyErr {
+				t.Errorf("Verify() error = %v, wantErr %v", err, tt.wantVerifyErr)
+			}
+		})
+	}
+
+	if _, _, err := GenerateCert("test.example.com", WithPermittedDNSDomains("example.com")); err == nil {
+		t.Errorf("GenerateCert() expected error for name constraints without CA")
+	}
+}
+
+func TestGenerateSubjectKeyId(t *testing.T) {
+	dest := t.TempDir()
+	if err := Generate("cadomain.cert", dest, WithCA(), WithP256(), WithCertFileName("ca_cert.pem"), WithKeyFileName("ca_key.pem")); err != nil {
+		t.Fatalf("Generate() CA error = %v", err)
+	}
+
+	if err := Generate("test.example.com", dest, WithSignByParent(dest+"/ca_cert.pem", dest+"/ca_key.pem")); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	ca, err := ParsePemCertFile(dest + "/ca_cert.pem")
+	if err != nil {
+		t.Fatalf("ParsePemCertFile() error = %v", err)
+	}
+
+	leaf, err := ParsePemCertFile(dest + "/cert.pem")
+	if err != nil {
+		t.Fatalf("ParsePemCertFile() error = %v", err)
+	}
+
+	for _, cert := range []*x509.Certificate{ca, leaf} {
+		want, err := subjectKeyID(cert.PublicKey)
+		if err != nil {
+			t.Fatalf("subjectKeyID() error = %v", err)
+		}
+
+		if len(cert.SubjectKeyId) != 20 || !bytes.Equal(cert.SubjectKeyId, want) {
+			t.Errorf("SubjectKeyId = %x, want %x", cert.SubjectKeyId, want)
+		}
+	}
+
+	if !bytes.Equal(leaf.AuthorityKeyId, ca.SubjectKeyId) {
+		t.Errorf("AuthorityKeyId = %x, want %x", leaf.AuthorityKeyId, ca.SubjectKeyId)
+	}
+}
+
+func TestSubjectKeyIdMatchesOpenSSL(t *testing.T) {
+	cert, err := ParsePemCertFile("testdata/cert.pem")
+	if err != nil {
+		t.Fatalf("ParsePemCertFile() error = %v", err)
+	}
+
+	got, err := subjectKeyID(cert.PublicKey)
+	if err != nil {
+		t.Fatalf("subjectKeyID() error = %v", err)
+	}
+
+	if !bytes.Equal(got, cert.SubjectKeyId) {
+		t.Errorf("subjectKeyID() = %x, want %x", got, cert.SubjectKeyId)
+	}
+}
+
+func TestGenerateWithDEROutput(t *testing.T) {
+	dest := t.TempDir()
+	if err := Generate("test.example.com", dest, WithP256(), WithDEROutput()); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	certDER, err := os.ReadFile(dest + "/cert.der")
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		t.Fatalf("ParseCertificate() error = %v", err)
+	}
+
+	keyDER, err := os.ReadFile(dest + "/key.der")
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+
+	priv, err := x509.ParsePKCS8PrivateKey(keyDER)
+	if err != nil {
+		t.Fatalf("ParsePKCS8PrivateKey() error = %v", err)
+	}
+
+	if !matchesPublicKey(priv, cert.PublicKey) {
+		t.Errorf("private key does not match certificate")
+	}
+
+	for _, name := range []string{"cert.pem", "key.pem"} {
+		if _, err = os.Stat(dest + "/" + name); !os.IsNotExist(err) {
+			t.Errorf("%s should not be written with WithDEROutput", name)
+		}
+	}
+}
+
+func TestGenerateWithNoClobber(t *testing.T) {
+	dest := t.TempDir()
+	if err := Generate("test.example.com", dest, WithP256(), WithNoClobber()); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	certPEM, err := os.ReadFile(dest + "/cert.pem")
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+
+	if err = Generate("test.example.com", dest, WithP256(), WithNoClobber()); err == nil {
+		t.Fatalf("Generate() expected error for existing files")
+	}
+
+	got, err := os.ReadFile(dest + "/cert.pem")
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+
+	if !bytes.Equal(got, certPEM) {
+		t.Errorf("Generate() overwrote existing certificate")
+	}
+
+	if err = os.Remove(dest + "/cert.pem"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+
+	if err = Generate("test.example.com", dest, WithP256(), WithNoClobber()); err == nil {
+		t.Fatalf("Generate() expected error for existing key file")
+	}
+
+	if _, err = os.Stat(dest + "/cert.pem"); !os.IsNotExist(err) {
+		t.Errorf("Generate() wrote certificate although key file exists")
+	}
+
+	if err = Generate("test.example.com", dest, WithP256()); err != nil {
+		t.Errorf("Generate() without WithNoClobber error = %v", err)
+	}
+}
+
+func TestGenerateAtomicWrite(t *testing.T) {
+	dest := t.TempDir()
+	if err := Generate("test.example.com", dest, WithP256()); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	certPEM, err := os.ReadFile(dest + "/cert.pem")
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+
+	// the key cannot be written into a missing directory, which fails after the certificate is staged
+	if err = Generate("test.example.com", dest, WithP256(), WithKeyFileName("missing/key.pem")); err == nil {
+		t.Fatalf("Generate() expected error for unwritable key file")
+	}
+
+	got, err := os.ReadFile(dest + "/cert.pem")
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+
+	if !bytes.Equal(got, certPEM) {
+		t.Errorf("Generate() replaced the certificate although writing the key failed")
+	}
+
+	entries, err := os.ReadDir(dest)
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+
+	for _, entry := range entries {
+		if entry.Name() != "cert.pem" && entry.Name() != "key.pem" {
+			t.Errorf("Generate() left partial file %s", entry.Name())
+		}
+	}
+}
+
+func TestGenerateWithNotAfter(t *testing.T) {
+	notAfter := time.Date(2040, time.March, 4, 5, 6, 7, 890, time.UTC)
+	tests := []struct {
+		name          string
+		opts          []Option
+		wantNotBefore time.Time
+		wantErr       bool
+	}{
+		{
+			name: "not after only",
+			opts: []Option{WithNotAfter(notAfter)},
+		},
+		{
+			name: "takes precedence over duration",
+			opts: []Option{WithDuration(time.Hour), WithNotAfter(notAfter)},
+		},
+		{
+			name:          "with future start date",
+			opts:          []Option{WithStartDate("Jan 2 15:04:05 2035"), WithNotAfter(notAfter)},
+			wantNotBefore: time.Date(2035, time.January, 2, 15, 4, 5, 0, time.UTC),
+		},
+		{
+			name:    "before start date",
+			opts:    []Option{WithStartDate("Jan 2 15:04:05 2041"), WithNotAfter(notAfter)},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, _, err := GenerateCert("test.example.com", append([]Option{WithP256()}, tt.opts...)...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenerateCert() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if want := notAfter.Truncate(time.Second); !cert.NotAfter.Equal(want) {
+				t.Errorf("NotAfter = %v, want %v", cert.NotAfter, want)
+			}
+
+			if !tt.wantNotBefore.IsZero() && !cert.NotBefore.Equal(tt.wantNotBefore) {
+				t.Errorf("NotBefore = %v, want %v", cert.NotBefore, tt.wantNotBefore)
+			}
+		})
+	}
+}
+
+func TestGenerateWithStartDateLayouts(t *testing.T) {
+	tests := []struct {
+		name      string
+		startDate string
+		want      time.Time
+		wantErr   bool
+	}{
+		{
+			name:      "legacy layout",
+			startDate: "Jan 2 15:04:05 2030",
+			want:      time.Date(2030, time.January, 2, 15, 4, 5, 0, time.UTC),
+		},
+		{
+			name:      "RFC3339",
+			startDate: "2030-01-02T15:04:05Z",
+			want:      time.Date(2030, time.January, 2, 15, 4, 5, 0, time.UTC),
+		},
+		{
+			name:      "RFC3339 with offset",
+			startDate: "2030-01-02T17:04:05+02:00",
+			want:      time.Date(2030, time.January, 2, 15, 4, 5, 0, time.UTC),
+		},
+		{
+			name:      "unparseable",
+			startDate: "02/01/2030",
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, _, err := GenerateCert("test.example.com", WithP256(), WithStartDate(tt.startDate))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenerateCert() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !strings.Contains(err.Error(), time.RFC3339) {
+					t.Errorf("GenerateCert() error = %v, want accepted formats listed", err)
+				}
+				return
+			}
+
+			if !cert.NotBefore.Equal(tt.want) {
+				t.Errorf("NotBefore = %v, want %v", cert.NotBefore, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateRSABits(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []Option
+		wantBits int
+		wantErr  bool
+	}{
+		{
+			name:     "default",
+			wantBits: 2048,
+		},
+		{
+			name:     "3072 bits",
+			opts:     []Option{WithRSABits(3072)},
+			wantBits: 3072,
+		},
+		{
+			name:    "1024 bits rejected",
+			opts:    []Option{WithRSABits(1024)},
+			wantErr: true,
+		},
+		{
+			name:    "negative bits rejected",
+			opts:    []Option{WithRSABits(-1)},
+			wantErr: true,
+		},
+		{
+			name:     "insecure 1024 bits",
+			opts:     []Option{WithInsecureRSABits(1024)},
+			wantBits: 1024,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, priv, err := GenerateCert("test.example.com", tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenerateCert() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			rsaKey, ok := priv.(*rsa.PrivateKey)
+			if !ok {
+				t.Fatalf("GenerateCert() key type = %T, want *rsa.PrivateKey", priv)
+			}
+
+			if got := rsaKey.N.BitLen(); got != tt.wantBits {
+				t.Errorf("key size = %d, want %d", got, tt.wantBits)
+			}
+		})
+	}
+}
+
+func TestGenerateWithRand(t *testing.T) {
+	generate := func() ([]byte, []byte) {
+		var certOut, keyOut bytes.Buffer
+		err := GenerateTo("test.example.com", &certOut, &keyOut,
+			WithED25519(),
+			WithRand(mrand.New(mrand.NewSource(42))),
+			WithStartDate("2030-01-02T15:04:05Z"),
+			WithDuration(time.Hour),
+		)
+		if err != nil {
+			t.Fatalf("GenerateTo() error = %v", err)
+		}
+
+		return certOut.Bytes(), keyOut.Bytes()
+	}
+
+	cert1, key1 := generate()
+	cert2, key2 := generate()
+
+	if !bytes.Equal(cert1, cert2) {
+		t.Errorf("GenerateTo() certificates differ with the same seeded reader")
+	}
+
+	if !bytes.Equal(key1, key2) {
+		t.Errorf("GenerateTo() keys differ with the same seeded reader")
+	}
+}
+
+func TestGenerateContextCancelled(t *testing.T) {
+	dest := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := GenerateContext(ctx, "test.example.com", dest, WithP256())
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("GenerateContext() error = %v, want %v", err, context.Canceled)
+	}
+
+	entries, err := os.ReadDir(dest)
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+
+	if len(entries) != 0 {
+		t.Errorf("GenerateContext() wrote %d files with a cancelled context", len(entries))
+	}
+}
+
+func TestGenerateWithSignatureAlgorithm(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		want    x509.SignatureAlgorithm
+		wantErr bool
+	}{
+		{
+			name: "default RSA",
+			want: x509.SHA256WithRSA,
+		},
+		{
+			name: "RSA-PSS",
+			opts: []Option{WithSignatureAlgorithm(x509.SHA256WithRSAPSS)},
+			want: x509.SHA256WithRSAPSS,
+		},
+		{
+			name: "RSA SHA512",
+			opts: []Option{WithSignatureAlgorithm(x509.SHA512WithRSA)},
+			want: x509.SHA512WithRSA,
+		},
+		{
+			name: "ECDSA SHA384",
+			opts: []Option{WithP256(), WithSignatureAlgorithm(x509.ECDSAWithSHA384)},
+			want: x509.ECDSAWithSHA384,
+		},
+		{
+			name: "Ed25519",
+			opts: []Option{WithED25519(), WithSignatureAlgorithm(x509.PureEd25519)},
+			want: x509.PureEd25519,
+		},
+		{
+			name:    "ECDSA algorithm with RSA key",
+			opts:    []Option{WithSignatureAlgorithm(x509.ECDSAWithSHA256)},
+			wantErr: true,
+		},
+		{
+			name:    "RSA-PSS with ECDSA key",
+			opts:    []Option{WithP256(), WithSignatureAlgorithm(x509.SHA256WithRSAPSS)},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, _, err := GenerateCert("test.example.com", tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenerateCert() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if cert.SignatureAlgorithm != tt.want {
+				t.Errorf("SignatureAlgorithm = %v, want %v", cert.SignatureAlgorithm, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateWildcardHosts(t *testing.T) {
+	tests := []struct {
+		name    string
+		host    string
+		wantErr bool
+	}{
+		{name: "wildcard and apex", host: "*.example.com,example.com"},
+		{name: "wildcard subdomain", host: "*.dev.example.com"},
+		{name: "double wildcard", host: "*.*.com", wantErr: true},
+		{name: "wildcard not leftmost", host: "foo.*.com", wantErr: true},
+		{name: "partial label wildcard", host: "f*.example.com", wantErr: true},
+		{name: "wildcard of top level domain", host: "*.com", wantErr: true},
+		{name: "bare wildcard", host: "*", wantErr: true},
+		{name: "wildcard with empty label", host: "*..com", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, _, err := GenerateCert(tt.host, WithP256())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenerateCert() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if want := strings.Split(tt.host, ","); !reflect.DeepEqual(cert.DNSNames, want) {
+				t.Errorf("DNSNames = %v, want %v", cert.DNSNames, want)
+			}
+		})
+	}
+}
+
+func TestGenerateNormalizesHosts(t *testing.T) {
+	cert, _, err := GenerateCert("example.com,EXAMPLE.com,Www.Example.com,example.com,127.0.0.1,::ffff:127.0.0.1,::1,0:0:0:0:0:0:0:1", WithP256())
+	if err != nil {
+		t.Fatalf("GenerateCert() error = %v", err)
+	}
+
+	if want := []string{"example.com", "www.example.com"}; !reflect.DeepEqual(cert.DNSNames, want) {
+		t.Errorf("DNSNames = %v, want %v", cert.DNSNames, want)
+	}
+
+	want := []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("::1")}
+	if len(cert.IPAddresses) != len(want) {
+		t.Fatalf("IPAddresses = %v, want %v", cert.IPAddresses, want)
+	}
+
+	for i, ip := range want {
+		if !cert.IPAddresses[i].Equal(ip) {
+			t.Errorf("IPAddresses = %v, want %v", cert.IPAddresses, want)
+		}
+	}
+}
+
+func TestGenerateTrimsHosts(t *testing.T) {
+	tests := []struct {
+		name    string
+		host    string
+		wantErr bool
+	}{
+		{name: "spaces after commas", host: "a.com, b.com, 10.0.0.1"},
+		{name: "trailing commas", host: " a.com ,b.com,,10.0.0.1, ,"},
+		{name: "only separators", host: " , ,", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, _, err := GenerateCert(tt.host, WithP256())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenerateCert() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if want := []string{"a.com", "b.com"}; !reflect.DeepEqual(cert.DNSNames, want) {
+				t.Errorf("DNSNames = %q, want %q", cert.DNSNames, want)
+			}
+
+			if len(cert.IPAddresses) != 1 || !cert.IPAddresses[0].Equal(net.ParseIP("10.0.0.1")) {
+				t.Errorf("IPAddresses = %v, want [10.0.0.1]", cert.IPAddresses)
+			}
+		})
+	}
+}
+
+func TestGenerateWithIPRange(t *testing.T) {
+	tests := []struct {
+		name    string
+		cidr    string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "IPv4 /30",
+			cidr: "10.0.0.0/30",
+			want: []string{"10.0.0.0", "10.0.0.1", "10.0.0.2", "10.0.0.3"},
+		},
+		{
+			name: "IPv4 /32",
+			cidr: "192.168.1.7/32",
+			want: []string{"192.168.1.7"},
+		},
+		{
+			name: "IPv6 /127",
+			cidr: "2001:db8::/127",
+			want: []string{"2001:db8::", "2001:db8::1"},
+		},
+		{name: "invalid CIDR", cidr: "10.0.0.0/33", wantErr: true},
+		{name: "not a network address", cidr: "10.0.0.1/30", wantErr: true},
+		{name: "too large", cidr: "10.0.0.0/16", wantErr: true},
+		{name: "too large IPv6", cidr: "2001:db8::/64", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, _, err := GenerateCert("test.example.com", WithP256(), WithIPRange(tt.cidr))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenerateCert() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			var got []string
+			for _, ip := range cert.IPAddresses {
+				got = append(got, ip.String())
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("IPAddresses = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateWithSubjectFields(t *testing.T) {
+	dest := t.TempDir()
+	err := Generate("test.example.com", dest,
+		WithP256(),
+		WithOrganization("Example Inc"),
+		WithCommonName("test.example.com"),
+		WithOrganizationalUnit("Platform", "Security"),
+		WithCountry("NL"),
+		WithProvince("Noord-Holland"),
+		WithLocality("Amsterdam"),
+	)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	cert, err := ParsePemCertFile(dest + "/cert.pem")
+	if err != nil {
+		t.Fatalf("ParsePemCertFile() error = %v", err)
+	}
+
+	got := cert.Subject
+	want := pkix.Name{
+		CommonName:         "test.example.com",
+		Organization:       []string{"Example Inc"},
+		OrganizationalUnit: []string{"Platform", "Security"},
+		Country:            []string{"NL"},
+		Province:           []string{"Noord-Holland"},
+		Locality:           []string{"Amsterdam"},
+	}
+	got.Names = nil
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Subject = %+v, want %+v", got, want)
+	}
+}
+
+func TestVerifyWithUsage(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		usages  []x509.ExtKeyUsage
+		wantErr bool
+	}{
+		{
+			name:   "server cert for server auth",
+			usages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		},
+		{
+			name:    "server cert for client auth",
+			usages:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+			wantErr: true,
+		},
+		{
+			name:   "client cert for client auth",
+			opts:   []Option{WithClientAuth()},
+			usages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		},
+		{
+			name:   "any usage",
+			usages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			// without extended key usages the CA does not restrict the usages of its leaves
+			if err := Generate("cadomain.cert", dest, WithCA(), WithP256(), WithExtKeyUsage(), WithCertFileName("ca_cert.pem"), WithKeyFileName("ca_key.pem")); err != nil {
+				t.Fatalf("Generate() CA error = %v", err)
+			}
+
+			opts := append([]Option{WithP256(), WithSignByParent(dest+"/ca_cert.pem", dest+"/ca_key.pem")}, tt.opts...)
+			if err := Generate("test.example.com", dest, opts...); err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+
+			err := VerifyWithUsage(dest+"/ca_cert.pem", dest+"/cert.pem", "test.example.com", tt.usages)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("VerifyWithUsage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyWithVerifyTime(t *testing.T) {
+	dest := t.TempDir()
+	if err := Generate("cadomain.cert", dest, WithCA(), WithP256(), WithStartDate("2030-01-01T00:00:00Z"), WithDuration(365*24*time.Hour), WithCertFileName("ca_cert.pem"), WithKeyFileName("ca_key.pem")); err != nil {
+		t.Fatalf("Generate() CA error = %v", err)
+	}
+
+	if err := Generate("test.example.com", dest, WithP256(), WithStartDate("2030-02-01T00:00:00Z"), WithDuration(30*24*time.Hour), WithSignByParent(dest+"/ca_cert.pem", dest+"/ca_key.pem")); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		at      time.Time
+		wantErr bool
+	}{
+		{name: "within validity", at: time.Date(2030, time.February, 15, 0, 0, 0, 0, time.UTC)},
+		{name: "before NotBefore", at: time.Date(2030, time.January, 15, 0, 0, 0, 0, time.UTC), wantErr: true},
+		{name: "after NotAfter", at: time.Date(2030, time.March, 15, 0, 0, 0, 0, time.UTC), wantErr: true},
+		{name: "now", at: time.Now(), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyWithOptions(dest+"/ca_cert.pem", dest+"/cert.pem", "test.example.com", WithVerifyTime(tt.at))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("VerifyWithOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerateSentinelErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		host string
+		opts []Option
+		want error
+	}{
+		{
+			name: "missing host",
+			host: "",
+			want: ErrMissingHost,
+		},
+		{
+			name: "blank host",
+			host: " , ",
+			want: ErrMissingHost,
+		},
+		{
+			name: "unrecognized curve",
+			host: "test.example.com",
+			opts: []Option{func(o *options) { o.ecdsaCurve = "P192" }},
+			want: ErrUnrecognizedCurve,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Generate(tt.host, t.TempDir(), tt.opts...); !errors.Is(err, tt.want) {
+				t.Errorf("Generate() error = %v, want %v", err, tt.want)
+			}
+
+			if err := GenerateCSR(tt.host, t.TempDir(), tt.opts...); !errors.Is(err, tt.want) {
+				t.Errorf("GenerateCSR() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateWrapsErrors(t *testing.T) {
+	dest := t.TempDir()
+	if err := Generate("cadomain.cert", dest, WithCA(), WithP256()); err != nil {
+		t.Fatalf("Generate() CA error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{
+			name: "missing parent certificate",
+			opts: []Option{WithSignByParent(dest+"/missing.pem", dest+"/key.pem")},
+		},
+		{
+			name: "missing parent key",
+			opts: []Option{WithSignByParent(dest+"/cert.pem", dest+"/missing.pem")},
+		},
+		{
+			name: "missing existing key",
+			opts: []Option{WithExistingKey(dest + "/missing.pem")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Generate("test.example.com", t.TempDir(), tt.opts...)
+			if !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("Generate() error = %v, want %v", err, os.ErrNotExist)
+			}
+		})
+	}
+}
+
+func TestGenerateRejectsNonCAParent(t *testing.T) {
+	tests := []struct {
+		name       string
+		parentOpts []Option
+		wantErr    bool
+	}{
+		{
+			name:       "CA parent",
+			parentOpts: []Option{WithCA()},
+		},
+		{
+			name:    "leaf parent",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			parentOpts := append([]Option{WithP256(), WithCertFileName("parent_cert.pem"), WithKeyFileName("parent_key.pem")}, tt.parentOpts...)
+			if err := Generate("parent.example.com", dest, parentOpts...); err != nil {
+				t.Fatalf("Generate() parent error = %v", err)
+			}
+
+			err := Generate("test.example.com", dest, WithP256(), WithSignByParent(dest+"/parent_cert.pem", dest+"/parent_key.pem"))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Generate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerateRejectsParentWithoutCertSign(t *testing.T) {
+	dest := t.TempDir()
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "parent"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &priv.PublicKey, priv)
+	if err != nil {
+		t.Fatalf("CreateCertificate() error = %v", err)
+	}
+
+	keyBytes, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey() error = %v", err)
+	}
+
+	if err = os.WriteFile(dest+"/parent_cert.pem", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	if err = os.WriteFile(dest+"/parent_key.pem", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyBytes}), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	if err = Generate("test.example.com", dest, WithP256(), WithSignByParent(dest+"/parent_cert.pem", dest+"/parent_key.pem")); err == nil {
+		t.Errorf("Generate() expected error for parent without CertSign key usage")
+	}
+}
+
+func TestGenerateRejectsMismatchedParentKey(t *testing.T) {
+	dest := t.TempDir()
+	if err := Generate("cadomain.cert", dest, WithCA(), WithP256(), WithCertFileName("ca_cert.pem"), WithKeyFileName("ca_key.pem")); err != nil {
+		t.Fatalf("Generate() CA error = %v", err)
+	}
+
+	if err := Generate("other.cert", dest, WithCA(), WithP256(), WithCertFileName("other_cert.pem"), WithKeyFileName("other_key.pem")); err != nil {
+		t.Fatalf("Generate() other CA error = %v", err)
+	}
+
+	err := Generate("test.example.com", dest, WithP256(), WithSignByParent(dest+"/ca_cert.pem", dest+"/other_key.pem"))
+	if err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("Generate() error = %v, want key mismatch error", err)
+	}
+
+	if _, err = os.Stat(dest + "/cert.pem"); !os.IsNotExist(err) {
+		t.Errorf("Generate() wrote a certificate signed with a mismatched key")
+	}
+}
+
+func TestGenerateHosts(t *testing.T) {
+	tests := []struct {
+		name    string
+		hosts   []string
+		wantDNS []string
+		wantIPs []string
+		wantErr bool
+	}{
+		{
+			name:    "mixed DNS names and IPs",
+			hosts:   []string{"a.example.com", "10.0.0.1", "b.example.com", "::1"},
+			wantDNS: []string{"a.example.com", "b.example.com"},
+			wantIPs: []string{"10.0.0.1", "::1"},
+		},
+		{
+			name:    "only IPs",
+			hosts:   []string{"127.0.0.1"},
+			wantIPs: []string{"127.0.0.1"},
+		},
+		{
+			name:    "surrounding whitespace and blank entries",
+			hosts:   []string{" a.example.com ", "", "  "},
+			wantDNS: []string{"a.example.com"},
+		},
+		{
+			name:    "no hosts",
+			wantErr: true,
+		},
+		{
+			name:    "empty host",
+			hosts:   []string{""},
+			wantErr: true,
+		},
+		{
+			name:    "blank hosts",
+			hosts:   []string{"  ", "\t"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			err := GenerateHosts(tt.hosts, dest, WithP256())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenerateHosts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			cert, err := ParsePemCertFile(dest + "/cert.pem")
+			if err != nil {
+				t.Fatalf("ParsePemCertFile() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(cert.DNSNames, tt.wantDNS) {
+				t.Errorf("DNSNames = %v, want %v", cert.DNSNames, tt.wantDNS)
+			}
+
+			var ips []string
+			for _, ip := range cert.IPAddresses {
+				ips = append(ips, ip.String())
+			}
+
+			if !reflect.DeepEqual(ips, tt.wantIPs) {
+				t.Errorf("IPAddresses = %v, want %v", ips, tt.wantIPs)
+			}
+		})
+	}
+}
+
+func TestGenerateWithCombinedOutput(t *testing.T) {
+	dest := t.TempDir()
+	if err := Generate("test.example.com", dest, WithP256(), WithCombinedOutput("combined.pem")); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	data, err := os.ReadFile(dest + "/combined.pem")
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+
+	priv, err := ParsePemKey(data)
+	if err != nil {
+		t.Fatalf("ParsePemKey() error = %v", err)
+	}
+
+	_, rest := pem.Decode(data)
+	cert, err := ParsePemCert(rest)
+	if err != nil {
+		t.Fatalf("ParsePemCert() error = %v", err)
+	}
+
+	if !matchesPublicKey(priv, cert.PublicKey) {
+		t.Errorf("private key does not match certificate")
+	}
+
+	for _, name := range []string{"cert.pem", "key.pem"} {
+		if _, err = os.Stat(dest + "/" + name); !os.IsNotExist(err) {
+			t.Errorf("%s should not be written with WithCombinedOutput", name)
+		}
+	}
+
+	if err = Generate("test.example.com", dest, WithP256(), WithCombinedOutput("combined.der"), WithDEROutput()); err == nil {
+		t.Errorf("Generate() expected error for combined DER output")
+	}
+}
+
+func TestGenerateWithCurve(t *testing.T) {
+	tests := []struct {
+		name      string
+		curve     string
+		wantCurve elliptic.Curve
+		wantErr   error
+	}{
+		{name: "P224", curve: CurveP224, wantCurve: elliptic.P224()},
+		{name: "P256", curve: CurveP256, wantCurve: elliptic.P256()},
+		{name: "P384", curve: CurveP384, wantCurve: elliptic.P384()},
+		{name: "P521", curve: CurveP521, wantCurve: elliptic.P521()},
+		{name: "unknown", curve: "P192", wantErr: ErrUnrecognizedCurve},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, priv, err := GenerateCert("test.example.com", WithCurve(tt.curve))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GenerateCert() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			key, ok := priv.(*ecdsa.PrivateKey)
+			if !ok {
+				t.Fatalf("GenerateCert() key type = %T, want *ecdsa.PrivateKey", priv)
+			}
+
+			if key.Curve != tt.wantCurve {
+				t.Errorf("curve = %v, want %v", key.Curve.Params().Name, tt.wantCurve.Params().Name)
+			}
+		})
+	}
+}
+
+func TestGenerateWithoutBasicConstraints(t *testing.T) {
+	oidBasicConstraints := asn1.ObjectIdentifier{2, 5, 29, 19}
+	tests := []struct {
+		name string
+		opts []Option
+		want bool
+	}{
+		{
+			name: "default leaf",
+			want: true,
+		},
+		{
+			name: "leaf without basic constraints",
+			opts: []Option{WithoutBasicConstraints()},
+			want: false,
+		},
+		{
+			name: "CA keeps basic constraints",
+			opts: []Option{WithoutBasicConstraints(), WithCA()},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, _, err := GenerateCert("test.example.com", append([]Option{WithP256()}, tt.opts...)...)
+			if err != nil {
+				t.Fatalf("GenerateCert() error = %v", err)
+			}
+
+			var got bool
+			for _, ext := range cert.Extensions {
+				if ext.Id.Equal(oidBasicConstraints) {
+					got = true
+				}
+			}
+
+			if got != tt.want {
+				t.Errorf("basic constraints extension present = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateWithClockSkew(t *testing.T) {
+	start := time.Date(2030, time.January, 2, 15, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name          string
+		opts          []Option
+		wantNotBefore time.Time
+	}{
+		{
+			name:          "without skew",
+			wantNotBefore: start,
+		},
+		{
+			name:          "with five minutes skew",
+			opts:          []Option{WithClockSkew(5 * time.Minute)},
+			wantNotBefore: start.Add(-5 * time.Minute),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]Option{WithP256(), WithStartDate(start.Format(time.RFC3339)), WithDuration(time.Hour)}, tt.opts...)
+			cert, _, err := GenerateCert("test.example.com", opts...)
+			if err != nil {
+				t.Fatalf("GenerateCert() error = %v", err)
+			}
+
+			if !cert.NotBefore.Equal(tt.wantNotBefore) {
+				t.Errorf("NotBefore = %v, want %v", cert.NotBefore, tt.wantNotBefore)
+			}
+
+			if want := start.Add(time.Hour); !cert.NotAfter.Equal(want) {
+				t.Errorf("NotAfter = %v, want %v", cert.NotAfter, want)
+			}
+		})
+	}
+}
+
+func TestGenerateWithSubjectSerialNumber(t *testing.T) {
+	cert, _, err := GenerateCert("test.example.com", WithP256(), WithSubjectSerialNumber("DEVICE-0042"), WithSerialNumber(big.NewInt(7)))
+	if err != nil {
+		t.Fatalf("GenerateCert() error = %v", err)
+	}
+
+	if cert.Subject.SerialNumber != "DEVICE-0042" {
+		t.Errorf("Subject.SerialNumber = %q, want %q", cert.Subject.SerialNumber, "DEVICE-0042")
+	}
+
+	if cert.SerialNumber.Cmp(big.NewInt(7)) != 0 {
+		t.Errorf("SerialNumber = %v, want 7", cert.SerialNumber)
+	}
+}
+
+func TestGenerateCAWithoutSANs(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr bool
+	}{
+		{
+			name: "CA with common name",
+			opts: []Option{WithCA(), WithCommonName("Test Root CA")},
+		},
+		{
+			name:    "CA without common name",
+			opts:    []Option{WithCA()},
+			wantErr: true,
+		},
+		{
+			name:    "leaf with common name",
+			opts:    []Option{WithCommonName("test.example.com")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			opts := append([]Option{WithP256(), WithCertFileName("ca_cert.pem"), WithKeyFileName("ca_key.pem")}, tt.opts...)
+			err := Generate("", dest, opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Generate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			ca, err := ParsePemCertFile(dest + "/ca_cert.pem")
+			if err != nil {
+				t.Fatalf("ParsePemCertFile() error = %v", err)
+			}
+
+			if len(ca.DNSNames) != 0 || len(ca.IPAddresses) != 0 {
+				t.Errorf("CA SANs = %v %v, want none", ca.DNSNames, ca.IPAddresses)
+			}
+
+			if ca.Subject.CommonName != "Test Root CA" {
+				t.Errorf("Subject.CommonName = %q, want %q", ca.Subject.CommonName, "Test Root CA")
+			}
+
+			if err = Generate("test.example.com", dest, WithP256(), WithSignByParent(dest+"/ca_cert.pem", dest+"/ca_key.pem")); err != nil {
+				t.Fatalf("Generate() leaf error = %v", err)
+			}
+
+			if err = Verify(dest+"/ca_cert.pem", dest+"/cert.pem", "test.example.com"); err != nil {
+				t.Errorf("Verify() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		host    string
+		opts    []Option
+		wantErr bool
+	}{
+		{
+			name: "valid options",
+			host: "test.example.com",
+			opts: []Option{WithP256()},
+		},
+		{
+			name:    "bad start date",
+			host:    "test.example.com",
+			opts:    []Option{WithStartDate("yesterday")},
+			wantErr: true,
+		},
+		{
+			name:    "bad curve",
+			host:    "test.example.com",
+			opts:    []Option{WithCurve("P192")},
+			wantErr: true,
+		},
+		{
+			name:    "PKCS#1 with ECDSA key",
+			host:    "test.example.com",
+			opts:    []Option{WithP256(), WithPKCS1()},
+			wantErr: true,
+		},
+		{
+			name:    "missing host",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(tt.host, tt.opts...); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			for _, name := range []string{"cert.pem", "key.pem"} {
+				if _, err := os.Stat(name); !os.IsNotExist(err) {
+					t.Errorf("Validate() wrote %s", name)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateWithSubjectEmail(t *testing.T) {
+	cert, _, err := GenerateCert("test.example.com",
+		WithP256(),
+		WithOrganizationalUnit("Platform", "Security", "Operations"),
+		WithSubjectEmail("admin@example.com"),
+	)
+	if err != nil {
+		t.Fatalf("GenerateCert() error = %v", err)
+	}
+
+	if want := []string{"Platform", "Security", "Operations"}; !reflect.DeepEqual(cert.Subject.OrganizationalUnit, want) {
+		t.Errorf("Subject.OrganizationalUnit = %v, want %v", cert.Subject.OrganizationalUnit, want)
+	}
+
+	var email any
+	for _, name := range cert.Subject.Names {
+		if name.Type.Equal(oidEmailAddress) {
+			email = name.Value
+		}
+	}
+
+	if email != "admin@example.com" {
+		t.Errorf("subject emailAddress = %v, want %v", email, "admin@example.com")
+	}
+
+	if len(cert.EmailAddresses) != 0 {
+		t.Errorf("EmailAddresses = %v, want no SAN", cert.EmailAddresses)
+	}
+}
+
+func TestGenerateWithSEC1(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr bool
+	}{
+		{
+			name: "with P256 key",
+			opts: []Option{WithSEC1(), WithP256()},
+		},
+		{
+			name: "with P384 key",
+			opts: []Option{WithSEC1(), WithP384()},
+		},
+		{
+			name:    "with RSA key",
+			opts:    []Option{WithSEC1()},
+			wantErr: true,
+		},
+		{
+			name:    "with Ed25519 key",
+			opts:    []Option{WithSEC1(), WithED25519()},
+			wantErr: true,
+		},
+		{
+			name:    "with passphrase",
+			opts:    []Option{WithSEC1(), WithP256(), WithKeyPassphrase([]byte("secret"))},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			if err := Generate("test.example.com", dest, tt.opts...); (err != nil) != tt.wantErr {
+				t.Fatalf("Generate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			data, err := os.ReadFile(dest + "/key.pem")
+			if err != nil {
+				t.Fatalf("ReadFile() error = %v", err)
+			}
+
+			if block, _ := pem.Decode(data); block == nil || block.Type != "EC PRIVATE KEY" {
+				t.Fatalf("key.pem is not a SEC1 PEM block")
+			}
+
+			cert, err := ParsePemCertFile(dest + "/cert.pem")
+			if err != nil {
+				t.Fatalf("ParsePemCertFile() error = %v", err)
+			}
+
+			priv, err := ParsePemKeyFile(dest + "/key.pem")
+			if err != nil {
+				t.Fatalf("ParsePemKeyFile() error = %v", err)
+			}
+
+			ecKey, ok := priv.(*ecdsa.PrivateKey)
+			if !ok {
+				t.Fatalf("ParsePemKeyFile() key type = %T, want *ecdsa.PrivateKey", priv)
+			}
+
+			if !ecKey.PublicKey.Equal(cert.PublicKey) {
+				t.Errorf("ParsePemKeyFile() key does not match certificate")
+			}
+		})
+	}
+}
+
+func TestGenerateDER(t *testing.T) {
+	certDER, keyDER, err := GenerateDER("test.example.com", WithP256())
+	if err != nil {
+		t.Fatalf("GenerateDER() error = %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		t.Fatalf("ParseCertificate() error = %v", err)
+	}
+
+	priv, err := x509.ParsePKCS8PrivateKey(keyDER)
+	if err != nil {
+		t.Fatalf("ParsePKCS8PrivateKey() error = %v", err)
+	}
+
+	if !matchesPublicKey(priv, cert.PublicKey) {
+		t.Errorf("GenerateDER() key does not match certificate")
+	}
+
+	if want := []string{"test.example.com"}; !reflect.DeepEqual(cert.DNSNames, want) {
+		t.Errorf("DNSNames = %v, want %v", cert.DNSNames, want)
+	}
+
+	if _, _, err = GenerateDER(""); !errors.Is(err, ErrMissingHost) {
+		t.Errorf("GenerateDER() error = %v, want %v", err, ErrMissingHost)
+	}
+}
+
+func TestMatchesHostname(t *testing.T) {
+	dest := t.TempDir()
+	if err := Generate("*.example.com,api.test.com,127.0.0.1", dest, WithP256()); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	cert, err := ParsePemCertFile(dest + "/cert.pem")
+	if err != nil {
+		t.Fatalf("ParsePemCertFile() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		host string
+		want bool
+	}{
+		{"exact", "api.test.com", true},
+		{"wildcard", "www.example.com", true},
+		{"ip", "127.0.0.1", true},
+		{"wildcard apex", "example.com", false},
+		{"wildcard nested", "a.b.example.com", false},
+		{"no match", "other.com", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchesHostname(cert, tt.host); got != tt.want {
+				t.Errorf("MatchesHostname(%q) = %v, want %v", tt.host, got, tt.want)
+			}
+
+			got, err := FileMatchesHostname(dest+"/cert.pem", tt.host)
+			if err != nil {
+				t.Fatalf("FileMatchesHostname() error = %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("FileMatchesHostname(%q) = %v, want %v", tt.host, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := FileMatchesHostname(dest+"/missing.pem", "api.test.com"); err == nil {
+		t.Errorf("FileMatchesHostname() expected error for missing file")
+	}
+}
+
+func TestGenerateWithMaxValidity(t *testing.T) {
+	maxValidity := 90 * 24 * time.Hour
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr bool
+	}{
+		{
+			name: "within maximum",
+			opts: []Option{WithDuration(30 * 24 * time.Hour)},
+		},
+		{
+			name: "equal to maximum",
+			opts: []Option{WithDuration(maxValidity)},
+		},
+		{
+			name: "clock skew not counted",
+			opts: []Option{WithDuration(maxValidity), WithClockSkew(5 * time.Minute)},
+		},
+		{
+			name:    "over long duration",
+			opts:    []Option{WithDuration(90 * 365 * 24 * time.Hour)},
+			wantErr: true,
+		},
+		{
+			name:    "over long not after",
+			opts:    []Option{WithNotAfter(time.Now().Add(2 * maxValidity))},
+			wantErr: true,
+		},
+		{
+			name:    "default duration",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			opts := append([]Option{WithP256(), WithMaxValidity(maxValidity)}, tt.opts...)
+			err := Generate("test.example.com", dest, opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Generate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if _, statErr := os.Stat(dest + "/cert.pem"); tt.wantErr && !os.IsNotExist(statErr) {
+				t.Errorf("cert.pem should not be written when the validity is rejected")
+			}
+		})
+	}
+}
+
+func TestParsePemKeyFormats(t *testing.T) {
+	pkcs8, err := os.ReadFile("testdata/key.pem")
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+
+	block, _ := pem.Decode(pkcs8)
+	mislabelled := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: block.Bytes})
+
+	tests := []struct {
+		name     string
+		data     []byte
+		wantType string
+		wantErr  bool
+	}{
+		{
+			name:     "PKCS8",
+			data:     pkcs8,
+			wantType: "*ecdsa.PrivateKey",
+		},
+		{
+			name:     "PKCS1",
+			data:     mustReadFile(t, "testdata/rsa_pkcs1_key.pem"),
+			wantType: "*rsa.PrivateKey",
+		},
+		{
+			name:     "SEC1 with EC PARAMETERS",
+			data:     mustReadFile(t, "testdata/ec_sec1_key.pem"),
+			wantType: "*ecdsa.PrivateKey",
+		},
+		{
+			name:     "PKCS8 labelled as SEC1",
+			data:     mislabelled,
+			wantType: "*ecdsa.PrivateKey",
+		},
+		{
+			name:    "unsupported block type",
+			data:    pem.EncodeToMemory(&pem.Block{Type: "DSA PRIVATE KEY", Bytes: block.Bytes}),
+			wantErr: true,
+		},
+		{
+			name:    "EC PARAMETERS only",
+			data:    pem.EncodeToMemory(&pem.Block{Type: "EC PARAMETERS", Bytes: []byte{0x06, 0x08}}),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			priv, err := ParsePemKey(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePemKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got := fmt.Sprintf("%T", priv); !tt.wantErr && got != tt.wantType {
+				t.Errorf("ParsePemKey() key type = %s, want %s", got, tt.wantType)
+			}
+		})
+	}
+}
+
+func mustReadFile(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+
+	return data
+}
+
+func TestGenerateWithIssuer(t *testing.T) {
+	issuer := pkix.Name{CommonName: "Cross Root", Organization: []string{"Other Co"}}
+
+	caDir := t.TempDir()
+	if err := Generate("cadomain.cert", caDir, WithCA(), WithP256(), WithCommonName("Real Root")); err != nil {
+		t.Fatalf("Generate() CA error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		opts       []Option
+		wantIssuer string
+	}{
+		{
+			name:       "self-signed",
+			opts:       []Option{WithIssuer(issuer)},
+			wantIssuer: issuer.String(),
+		},
+		{
+			name:       "self-signed CA",
+			opts:       []Option{WithCA(), WithIssuer(issuer)},
+			wantIssuer: issuer.String(),
+		},
+		{
+			name:       "ignored with parent",
+			opts:       []Option{WithIssuer(issuer), WithSignByParent(caDir+"/cert.pem", caDir+"/key.pem")},
+			wantIssuer: "CN=Real Root,O=Acme Co",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, _, err := GenerateCert("test.example.com", append([]Option{WithP256()}, tt.opts...)...)
+			if err != nil {
+				t.Fatalf("GenerateCert() error = %v", err)
+			}
+
+			if got := cert.Issuer.String(); got != tt.wantIssuer {
+				t.Errorf("Issuer = %s, want %s", got, tt.wantIssuer)
+			}
+
+			if cert.Subject.String() == tt.wantIssuer {
+				t.Errorf("Subject = %s, should differ from the issuer", cert.Subject)
+			}
+		})
+	}
+}
+
+func TestGenerateWithUniqueNamesConcurrent(t *testing.T) {
+	const n = 8
+	dest := t.TempDir()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- Generate("test.example.com", dest, WithP256(), WithUniqueNames())
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+	}
+
+	certPaths, err := filepath.Glob(dest + "/cert-*.pem")
+	if err != nil {
+		t.Fatalf("Glob() error = %v", err)
+	}
+
+	if len(certPaths) != n {
+		t.Fatalf("got %d certificate files, want %d", len(certPaths), n)
+	}
+
+	for _, certPath := range certPaths {
+		cert, err := ParsePemCertFile(certPath)
+		if err != nil {
+			t.Fatalf("ParsePemCertFile() error = %v", err)
+		}
+
+		suffix := cert.SerialNumber.Text(16)
+		if want := dest + "/cert-" + suffix + ".pem"; certPath != want {
+			t.Errorf("certificate file = %s, want %s", certPath, want)
+		}
+
+		priv, err := ParsePemKeyFile(dest + "/key-" + suffix + ".pem")
+		if err != nil {
+			t.Fatalf("ParsePemKeyFile() error = %v", err)
+		}
+
+		if !matchesPublicKey(priv, cert.PublicKey) {
+			t.Errorf("key-%s.pem does not match %s", suffix, certPath)
+		}
+	}
+}
+
+func TestGenerateWithCreateDir(t *testing.T) {
+	base := t.TempDir()
+	if err := os.WriteFile(base+"/file", []byte("x"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		dest    string
+		opts    []Option
+		wantErr bool
+	}{
+		{
+			name: "nested missing directory",
+			dest: base + "/a/b/c",
+			opts: []Option{WithCreateDir()},
+		},
+		{
+			name: "existing directory",
+			dest: base,
+			opts: []Option{WithCreateDir()},
+		},
+		{
+			name:    "missing directory without option",
+			dest:    base + "/d/e",
+			wantErr: true,
+		},
+		{
+			name:    "destination is a file",
+			dest:    base + "/file",
+			opts:    []Option{WithCreateDir()},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Generate("test.example.com", tt.dest, append([]Option{WithP256()}, tt.opts...)...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Generate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if _, err = ParsePemCertFile(tt.dest + "/cert.pem"); err != nil {
+				t.Errorf("ParsePemCertFile() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestGenerateWithResult(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         []Option
+		wantCertFile string
+		wantKeyFile  string
+	}{
+		{
+			name:         "default file names",
+			wantCertFile: "cert.pem",
+			wantKeyFile:  "key.pem",
+		},
+		{
+			name:         "DER output",
+			opts:         []Option{WithDEROutput()},
+			wantCertFile: "cert.der",
+			wantKeyFile:  "key.der",
+		},
+		{
+			name:         "combined output",
+			opts:         []Option{WithCombinedOutput("server.pem")},
+			wantCertFile: "server.pem",
+			wantKeyFile:  "server.pem",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			res, err := GenerateWithResult("test.example.com", dest, append([]Option{WithP256()}, tt.opts...)...)
+			if err != nil {
+				t.Fatalf("GenerateWithResult() error = %v", err)
+			}
+
+			if want := dest + "/" + tt.wantCertFile; res.CertPath != want {
+				t.Errorf("CertPath = %s, want %s", res.CertPath, want)
+			}
+
+			if want := dest + "/" + tt.wantKeyFile; res.KeyPath != want {
+				t.Errorf("KeyPath = %s, want %s", res.KeyPath, want)
+			}
+
+			data, err := os.ReadFile(res.CertPath)
+			if err != nil {
+				t.Fatalf("ReadFile() error = %v", err)
+			}
+
+			cert, err := x509.ParseCertificate(data)
+			if certs, pemErr := ParsePemCertChain(data); pemErr == nil {
+				cert, err = certs[0], nil
+			}
+			if err != nil {
+				t.Fatalf("failed to parse %s: %v", res.CertPath, err)
+			}
+
+			if res.Serial.Cmp(cert.SerialNumber) != 0 {
+				t.Errorf("Serial = %v, want %v", res.Serial, cert.SerialNumber)
+			}
+
+			if !res.NotAfter.Equal(cert.NotAfter) {
+				t.Errorf("NotAfter = %v, want %v", res.NotAfter, cert.NotAfter)
+			}
+
+			if _, err = hex.DecodeString(res.Fingerprint); err != nil || res.Fingerprint != Fingerprint(cert) {
+				t.Errorf("Fingerprint = %s, want %s", res.Fingerprint, Fingerprint(cert))
+			}
+		})
+	}
+
+	if _, err := GenerateWithResult("", t.TempDir()); !errors.Is(err, ErrMissingHost) {
+		t.Errorf("GenerateWithResult() error = %v, want %v", err, ErrMissingHost)
+	}
+}
+
+func TestGenerateWithIntermediateCA(t *testing.T) {
+	tests := []struct {
+		name    string
+		intOpts func(rootCert, rootKey string) []Option
+	}{
+		{
+			name: "WithIntermediateCA",
+			intOpts: func(rootCert, rootKey string) []Option {
+				return []Option{WithIntermediateCA(rootCert, rootKey)}
+			},
+		},
+		{
+			name: "WithCA and WithSignByParent",
+			intOpts: func(rootCert, rootKey string) []Option {
+				return []Option{WithCA(), WithSignByParent(rootCert, rootKey)}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			rootCert, rootKey := dest+"/root_cert.pem", dest+"/root_key.pem"
+			intCert, intKey := dest+"/int_cert.pem", dest+"/int_key.pem"
+			if err := Generate("root.cert", dest, WithCA(), WithP256(), WithCertFileName("root_cert.pem"), WithKeyFileName("root_key.pem")); err != nil {
+				t.Fatalf("Generate() root error = %v", err)
+			}
+
+			opts := append([]Option{WithP256(), WithCertFileName("int_cert.pem"), WithKeyFileName("int_key.pem")}, tt.intOpts(rootCert, rootKey)...)
+			if err := Generate("intermediate.cert", dest, opts...); err != nil {
+				t.Fatalf("Generate() intermediate error = %v", err)
+			}
+
+			if err := Generate("test.example.com", dest, WithP256(), WithSignByParent(intCert, intKey)); err != nil {
+				t.Fatalf("Generate() leaf error = %v", err)
+			}
+
+			root, err := ParsePemCertFile(rootCert)
+			if err != nil {
+				t.Fatalf("ParsePemCertFile() error = %v", err)
+			}
+
+			intermediate, err := ParsePemCertFile(intCert)
+			if err != nil {
+				t.Fatalf("ParsePemCertFile() error = %v", err)
+			}
+
+			if !intermediate.IsCA {
+				t.Errorf("intermediate IsCA = false, want true")
+			}
+
+			if intermediate.KeyUsage&x509.KeyUsageCertSign == 0 {
+				t.Errorf("intermediate KeyUsage = %v, want CertSign", intermediate.KeyUsage)
+			}
+
+			if err = intermediate.CheckSignatureFrom(root); err != nil {
+				t.Errorf("intermediate is not signed by root: %v", err)
+			}
+
+			if err = VerifyChain(rootCert, dest+"/cert.pem", "test.example.com", intCert); err != nil {
+				t.Errorf("VerifyChain() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestGenerateWithValidFrom(t *testing.T) {
+	validFrom := time.Date(2030, time.June, 7, 8, 9, 10, 0, time.UTC)
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{
+			name: "valid from only",
+			opts: []Option{WithValidFrom(validFrom)},
+		},
+		{
+			name: "takes precedence over start date",
+			opts: []Option{WithStartDate("Jan 2 15:04:05 2035"), WithValidFrom(validFrom)},
+		},
+		{
+			name: "no parse error with invalid start date",
+			opts: []Option{WithValidFrom(validFrom), WithStartDate("not a date")},
+		},
+		{
+			name: "local time zone",
+			opts: []Option{WithValidFrom(validFrom.In(time.FixedZone("UTC+2", 2*60*60)))},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, _, err := GenerateCert("test.example.com", append([]Option{WithP256(), WithDuration(time.Hour)}, tt.opts...)...)
+			if err != nil {
+				t.Fatalf("GenerateCert() error = %v", err)
+			}
+
+			if !cert.NotBefore.Equal(validFrom) {
+				t.Errorf("NotBefore = %v, want %v", cert.NotBefore, validFrom)
+			}
+
+			if want := validFrom.Add(time.Hour); !cert.NotAfter.Equal(want) {
+				t.Errorf("NotAfter = %v, want %v", cert.NotAfter, want)
+			}
+		})
+	}
+}
+
+func TestGenerateWithoutKeyFile(t *testing.T) {
+	keyDir := t.TempDir()
+	if err := Generate("test.example.com", keyDir, WithP256()); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	keyPath := keyDir + "/key.pem"
+
+	tests := []struct {
+		name      string
+		opts      []Option
+		wantFiles []string
+		wantErr   bool
+	}{
+		{
+			name:      "with existing key",
+			opts:      []Option{WithExistingKey(keyPath), WithoutKeyFile()},
+			wantFiles: []string{"cert.pem"},
+		},
+		{
+			name:      "DER output",
+			opts:      []Option{WithExistingKey(keyPath), WithoutKeyFile(), WithDEROutput()},
+			wantFiles: []string{"cert.der"},
+		},
+		{
+			name:    "fresh key",
+			opts:    []Option{WithoutKeyFile()},
+			wantErr: true,
+		},
+		{
+			name:    "combined output",
+			opts:    []Option{WithExistingKey(keyPath), WithoutKeyFile(), WithCombinedOutput("server.pem")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			err := Generate("test.example.com", dest, append([]Option{WithP256()}, tt.opts...)...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Generate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err := Validate("test.example.com", append([]Option{WithP256()}, tt.opts...)...); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			entries, err := os.ReadDir(dest)
+			if err != nil {
+				t.Fatalf("ReadDir() error = %v", err)
+			}
+
+			var got []string
+			for _, e := range entries {
+				got = append(got, e.Name())
+			}
+
+			if !reflect.DeepEqual(got, tt.wantFiles) {
+				t.Errorf("written files = %v, want %v", got, tt.wantFiles)
+			}
+		})
+	}
+}
+
+func TestGenerateFileNamePlaceholders(t *testing.T) {
+	validFrom := time.Date(2030, time.June, 7, 8, 9, 10, 0, time.UTC)
+	serial := big.NewInt(0xabc)
+	tests := []struct {
+		name         string
+		host         string
+		opts         []Option
+		wantCertFile string
+		wantKeyFile  string
+	}{
+		{
+			name:         "host",
+			host:         "test.example.com",
+			opts:         []Option{WithCertFileName("{host}-cert.pem"), WithKeyFileName("{host}-key.pem")},
+			wantCertFile: "test.example.com-cert.pem",
+			wantKeyFile:  "test.example.com-key.pem",
+		},
+		{
+			name:         "wildcard host",
+			host:         "*.example.com",
+			opts:         []Option{WithCertFileName("{host}.pem")},
+			wantCertFile: "_.example.com.pem",
+			wantKeyFile:  "key.pem",
+		},
+		{
+			name:         "IPv6 host",
+			host:         "::1",
+			opts:         []Option{WithCertFileName("{host}.pem")},
+			wantCertFile: "__1.pem",
+			wantKeyFile:  "key.pem",
+		},
+		{
+			name:         "serial and date",
+			host:         "test.example.com",
+			opts:         []Option{WithCertFileName("cert-{serial}-{date}.pem"), WithKeyFileName("key-{serial}.pem")},
+			wantCertFile: "cert-abc-20300607.pem",
+			wantKeyFile:  "key-abc.pem",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			opts := append([]Option{WithP256(), WithValidFrom(validFrom), WithSerialNumber(serial)}, tt.opts...)
+			res, err := GenerateWithResult(tt.host, dest, opts...)
+			if err != nil {
+				t.Fatalf("GenerateWithResult() error = %v", err)
+			}
+
+			if want := dest + "/" + tt.wantCertFile; res.CertPath != want {
+				t.Errorf("CertPath = %s, want %s", res.CertPath, want)
+			}
+
+			if want := dest + "/" + tt.wantKeyFile; res.KeyPath != want {
+				t.Errorf("KeyPath = %s, want %s", res.KeyPath, want)
+			}
+
+			if _, err = ParsePemCertFile(dest + "/" + tt.wantCertFile); err != nil {
+				t.Errorf("ParsePemCertFile() error = %v", err)
+			}
+
+			if _, err = ParsePemKeyFile(dest + "/" + tt.wantKeyFile); err != nil {
+				t.Errorf("ParsePemKeyFile() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyChains(t *testing.T) {
+	dest := t.TempDir()
+	rootCert, rootKey := dest+"/root_cert.pem", dest+"/root_key.pem"
+	intCert, intKey := dest+"/int_cert.pem", dest+"/int_key.pem"
+	if err := Generate("root.cert", dest, WithCA(), WithP256(), WithCertFileName("root_cert.pem"), WithKeyFileName("root_key.pem")); err != nil {
+		t.Fatalf("Generate() root error = %v", err)
+	}
+
+	err := Generate("intermediate.cert", dest, WithIntermediateCA(rootCert, rootKey), WithP256(), WithCertFileName("int_cert.pem"), WithKeyFileName("int_key.pem"))
+	if err != nil {
+		t.Fatalf("Generate() intermediate error = %v", err)
+	}
+
+	if err = Generate("test.example.com", dest, WithP256(), WithSignByParent(rootCert, rootKey), WithCertFileName("leaf_cert.pem"), WithKeyFileName("leaf_key.pem")); err != nil {
+		t.Fatalf("Generate() leaf error = %v", err)
+	}
+
+	if err = Generate("test.example.com", dest, WithP256(), WithSignByParent(intCert, intKey)); err != nil {
+		t.Fatalf("Generate() leaf error = %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		certPath      string
+		dnsName       string
+		intermediates []string
+		wantLen       int
+		wantErr       bool
+	}{
+		{
+			name:     "signed by root",
+			certPath: dest + "/leaf_cert.pem",
+			dnsName:  "test.example.com",
+			wantLen:  2,
+		},
+		{
+			name:          "signed by intermediate",
+			certPath:      dest + "/cert.pem",
+			dnsName:       "test.example.com",
+			intermediates: []string{intCert},
+			wantLen:       3,
+		},
+		{
+			name:     "missing intermediate",
+			certPath: dest + "/cert.pem",
+			dnsName:  "test.example.com",
+			wantErr:  true,
+		},
+		{
+			name:     "wrong host",
+			certPath: dest + "/leaf_cert.pem",
+			dnsName:  "other.example.com",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chains, err := VerifyChains(rootCert, tt.certPath, tt.dnsName, tt.intermediates...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VerifyChains() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if len(chains) != 1 {
+				t.Fatalf("VerifyChains() returned %d chains, want 1", len(chains))
+			}
+
+			chain := chains[0]
+			if len(chain) != tt.wantLen {
+				t.Fatalf("chain length = %d, want %d", len(chain), tt.wantLen)
+			}
+
+			cert, err := ParsePemCertFile(tt.certPath)
+			if err != nil {
+				t.Fatalf("ParsePemCertFile() error = %v", err)
+			}
+
+			root, err := ParsePemCertFile(rootCert)
+			if err != nil {
+				t.Fatalf("ParsePemCertFile() error = %v", err)
+			}
+
+			if !chain[0].Equal(cert) || !chain[len(chain)-1].Equal(root) {
+				t.Errorf("chain should start with the certificate and end with the root")
+			}
+		})
+	}
+}
+
+func TestGenerateWithCodeSigning(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"ECDSA", []Option{WithP256(), WithCodeSigning()}},
+		{"RSA", []Option{WithRSABits(2048), WithCodeSigning()}},
+		{"after ClientAuth", []Option{WithP256(), WithClientAuth(), WithCodeSigning()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, _, err := GenerateCert("signer.example.com", tt.opts...)
+			if err != nil {
+				t.Fatalf("GenerateCert() error = %v", err)
+			}
+
+			if want := []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning}; !reflect.DeepEqual(cert.ExtKeyUsage, want) {
+				t.Errorf("ExtKeyUsage = %v, want %v", cert.ExtKeyUsage, want)
+			}
+
+			if cert.KeyUsage != x509.KeyUsageDigitalSignature {
+				t.Errorf("KeyUsage = %v, want %v", cert.KeyUsage, x509.KeyUsageDigitalSignature)
+			}
+
+			roots := x509.NewCertPool()
+			roots.AddCert(cert)
+			_, err = cert.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}})
+			if err == nil {
+				t.Errorf("Verify() server auth expected error for code signing certificate")
+			}
+		})
+	}
+}
+
+func TestGenerateWithPEMHeaders(t *testing.T) {
+	headers := map[string]string{"Comment": "issued for testing", "Owner": "Acme Co"}
+	tests := []struct {
+		name string
+		opts []Option
+		want map[string]string
+	}{
+		{
+			name: "default",
+			want: map[string]string{},
+		},
+		{
+			name: "with headers",
+			opts: []Option{WithPEMHeaders(headers)},
+			want: headers,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			if err := Generate("test.example.com", dest, append([]Option{WithP256()}, tt.opts...)...); err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+
+			data, err := os.ReadFile(dest + "/cert.pem")
+			if err != nil {
+				t.Fatalf("ReadFile() error = %v", err)
+			}
+
+			block, _ := pem.Decode(data)
+			if block == nil {
+				t.Fatalf("failed to decode certificate PEM")
+			}
+
+			if !reflect.DeepEqual(block.Headers, tt.want) {
+				t.Errorf("Headers = %v, want %v", block.Headers, tt.want)
+			}
+
+			if _, err = ParsePemCert(data); err != nil {
+				t.Errorf("ParsePemCert() error = %v", err)
+			}
+
+			keyData, err := os.ReadFile(dest + "/key.pem")
+			if err != nil {
+				t.Fatalf("ReadFile() error = %v", err)
+			}
+
+			if keyBlock, _ := pem.Decode(keyData); keyBlock == nil || len(keyBlock.Headers) != 0 {
+				t.Errorf("key PEM should not have headers")
+			}
+		})
+	}
+}
+
+func TestLoadKeyPair(t *testing.T) {
+	dest := t.TempDir()
+	if err := Generate("test.example.com", dest, WithP256()); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if err := Generate("other.example.com", dest, WithED25519(), WithCertFileName("other_cert.pem"), WithKeyFileName("other_key.pem")); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		certPath string
+		keyPath  string
+		wantErr  error
+	}{
+		{
+			name:     "matching pair",
+			certPath: dest + "/cert.pem",
+			keyPath:  dest + "/key.pem",
+		},
+		{
+			name:     "mismatched pair",
+			certPath: dest + "/cert.pem",
+			keyPath:  dest + "/other_key.pem",
+			wantErr:  ErrKeyMismatch,
+		},
+		{
+			name:     "mismatched pair reversed",
+			certPath: dest + "/other_cert.pem",
+			keyPath:  dest + "/key.pem",
+			wantErr:  ErrKeyMismatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, key, err := LoadKeyPair(tt.certPath, tt.keyPath)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("LoadKeyPair() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if cert == nil || !matchesPublicKey(key, cert.PublicKey) {
+				t.Errorf("LoadKeyPair() returned a key that does not match the certificate")
+			}
+		})
+	}
+
+	if _, _, err := LoadKeyPair(dest+"/missing.pem", dest+"/key.pem"); err == nil {
+		t.Errorf("LoadKeyPair() expected error for missing certificate")
+	}
+
+	if _, _, err := LoadKeyPair(dest+"/cert.pem", dest+"/missing.pem"); err == nil {
+		t.Errorf("LoadKeyPair() expected error for missing key")
+	}
+}
+
+func TestGenerateLocalhost(t *testing.T) {
+	dest := t.TempDir()
+	if err := GenerateLocalhost(dest, WithP256()); err != nil {
+		t.Fatalf("GenerateLocalhost() error = %v", err)
+	}
+
+	if err := Verify(dest+"/cert.pem", dest+"/cert.pem", "localhost"); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+
+	cert, err := ParsePemCertFile(dest + "/cert.pem")
+	if err != nil {
+		t.Fatalf("ParsePemCertFile() error = %v", err)
+	}
+
+	if want := []string{"localhost"}; !reflect.DeepEqual(cert.DNSNames, want) {
+		t.Errorf("DNSNames = %v, want %v", cert.DNSNames, want)
+	}
+
+	wantIPs := []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("::1")}
+	if len(cert.IPAddresses) != len(wantIPs) {
+		t.Fatalf("IPAddresses = %v, want %v", cert.IPAddresses, wantIPs)
+	}
+
+	for i, ip := range wantIPs {
+		if !cert.IPAddresses[i].Equal(ip) {
+			t.Errorf("IPAddresses[%d] = %v, want %v", i, cert.IPAddresses[i], ip)
+		}
+	}
+}
+
+func TestGenerateWithReverseDNS(t *testing.T) {
+	orig := lookupAddr
+	defer func() { lookupAddr = orig }()
+
+	lookupAddr = func(ctx context.Context, addr string) ([]string, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Errorf("reverse DNS lookup of %s without timeout", addr)
+		}
+
+		switch addr {
+		case "192.0.2.1":
+			return []string{"host1.example.com.", "alias.example.com."}, nil
+		case "192.0.2.2":
+			return []string{"Test.Example.com."}, nil
+		default:
+			return nil, errors.New("no PTR record")
+		}
+	}
+
+	tests := []struct {
+		name      string
+		host      string
+		opts      []Option
+		wantNames []string
+	}{
+		{
+			name:      "without option",
+			host:      "test.example.com,192.0.2.1",
+			wantNames: []string{"test.example.com"},
+		},
+		{
+			name:      "with PTR names",
+			host:      "test.example.com,192.0.2.1",
+			opts:      []Option{WithReverseDNS()},
+			wantNames: []string{"test.example.com", "host1.example.com", "alias.example.com"},
+		},
+		{
+			name:      "duplicate PTR name",
+			host:      "test.example.com,192.0.2.2",
+			opts:      []Option{WithReverseDNS()},
+			wantNames: []string{"test.example.com"},
+		},
+		{
+			name:      "lookup failure skipped",
+			host:      "test.example.com,192.0.2.3",
+			opts:      []Option{WithReverseDNS()},
+			wantNames: []string{"test.example.com"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, _, err := GenerateCert(tt.host, append([]Option{WithP256()}, tt.opts...)...)
+			if err != nil {
+				t.Fatalf("GenerateCert() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(cert.DNSNames, tt.wantNames) {
+				t.Errorf("DNSNames = %v, want %v", cert.DNSNames, tt.wantNames)
+			}
+
+			if len(cert.IPAddresses) != 1 {
+				t.Errorf("IPAddresses = %v, want the single IP host", cert.IPAddresses)
+			}
+		})
+	}
+}
+
+func TestGenerateWithReverseDNSRange(t *testing.T) {
+	origLookup, origTimeout := lookupAddr, reverseDNSTimeout
+	defer func() { lookupAddr, reverseDNSTimeout = origLookup, origTimeout }()
+	reverseDNSTimeout = 200 * time.Millisecond
+
+	var mu sync.Mutex
+	var running, maxRunning int
+	lookupAddr = func(ctx context.Context, addr string) ([]string, error) {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			running--
+			mu.Unlock()
+		}()
+
+		// the first addresses answer, the rest hang until the overall deadline
+		switch addr {
+		case "10.0.0.1":
+			return []string{"one.example.com."}, nil
+		case "10.0.0.2":
+			return []string{"two.example.com."}, nil
+		}
+
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	start := time.Now()
+	cert, _, err := GenerateCert("test.example.com", WithP256(), WithIPRange("10.0.0.0/24"), WithReverseDNS())
+	if err != nil {
+		t.Fatalf("GenerateCert() error = %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("GenerateCert() took %v, want the lookups bounded by one overall timeout", elapsed)
+	}
+
+	if maxRunning > reverseDNSConcurrency {
+		t.Errorf("concurrent lookups = %d, want at most %d", maxRunning, reverseDNSConcurrency)
+	}
+
+	want := []string{"test.example.com", "one.example.com", "two.example.com"}
+	if !reflect.DeepEqual(cert.DNSNames, want) {
+		t.Errorf("DNSNames = %v, want %v", cert.DNSNames, want)
+	}
+
+	if len(cert.IPAddresses) != 256 {
+		t.Errorf("IPAddresses = %d, want 256", len(cert.IPAddresses))
+	}
+}
+
+func TestVerifySystem(t *testing.T) {
+	if _, err := x509.SystemCertPool(); err != nil {
+		t.Skipf("system root store not available: %v", err)
+	}
+
+	dest := t.TempDir()
+	if err := Generate("test.example.com", dest, WithP256()); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if err := VerifySystem(dest+"/cert.pem", "test.example.com"); err == nil {
+		t.Errorf("VerifySystem() expected error for a self-signed certificate")
+	}
+
+	if err := VerifySystem(dest+"/cert.pem", "test.example.com", dest+"/missing.pem"); err == nil {
+		t.Errorf("VerifySystem() expected error for missing intermediate file")
+	}
+
+	if err := VerifySystem(dest+"/missing.pem", "test.example.com"); err == nil {
+		t.Errorf("VerifySystem() expected error for missing certificate")
+	}
+}
+
+func TestGenerateWithCRLF(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []Option
+		wantCRLF bool
+	}{
+		{
+			name: "default LF",
+		},
+		{
+			name:     "with CRLF",
+			opts:     []Option{WithCRLF()},
+			wantCRLF: true,
+		},
+		{
+			name:     "with CRLF and encrypted key",
+			opts:     []Option{WithCRLF(), WithKeyPassphrase([]byte("secret"))},
+			wantCRLF: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			if err := Generate("test.example.com", dest, append([]Option{WithP256()}, tt.opts...)...); err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+
+			for _, name := range []string{"cert.pem", "key.pem"} {
+				data, err := os.ReadFile(dest + "/" + name)
+				if err != nil {
+					t.Fatalf("ReadFile() error = %v", err)
+				}
+
+				lines := bytes.Count(data, []byte("\n"))
+				crlfLines := bytes.Count(data, []byte("\r\n"))
+				if tt.wantCRLF && crlfLines != lines {
+					t.Errorf("%s has %d CRLF line endings out of %d lines", name, crlfLines, lines)
+				}
+
+				if !tt.wantCRLF && crlfLines != 0 {
+					t.Errorf("%s has %d CRLF line endings, want none", name, crlfLines)
+				}
+			}
+
+			if _, err := ParsePemCertFile(dest + "/cert.pem"); err != nil {
+				t.Errorf("ParsePemCertFile() error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+//go:build unix
+
+package gcert
+
+import (
+	"os"
+	"testing"
+)
+
+func TestGenerateWithFileMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []Option
+		certMode os.FileMode
+		keyMode  os.FileMode
+	}{
+		{
+			name:     "default modes",
+			certMode: 0644,
+			keyMode:  0600,
+		},
+		{
+			name:     "custom modes",
+			opts:     []Option{WithFileMode(0640, 0400)},
+			certMode: 0640,
+			keyMode:  0400,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			opts := append([]Option{WithP256()}, tt.opts...)
+			if err := Generate("test.example.com", dest, opts...); err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+
+			for path, want := range map[string]os.FileMode{
+				dest + "/cert.pem": tt.certMode,
+				dest + "/key.pem":  tt.keyMode,
+			} {
+				info, err := os.Stat(path)
+				if err != nil {
+					t.Fatalf("Stat() error = %v", err)
+				}
+
+				if got := info.Mode().Perm(); got != want {
+					t.Errorf("%s mode = %v, want %v", path, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateWithCombinedOutputMode(t *testing.T) {
+	dest := t.TempDir()
+	if err := Generate("test.example.com", dest, WithP256(), WithCombinedOutput("combined.pem")); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	info, err := os.Stat(dest + "/combined.pem")
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+
+	if got := info.Mode().Perm(); got != 0600 {
+		t.Errorf("combined file mode = %v, want %v", got, os.FileMode(0600))
+	}
+}
@@ -0,0 +1,62 @@
+package gcert
+
+import (
+	"crypto/x509"
+	"fmt"
+	"sort"
+)
+
+// SameIdentity reports whether both certificates identify the same entity: equal subject,
+// DNS names, IP addresses, email addresses, URIs, CA flag and extended key usages. The
+// order of the names and usages, the serial number, the validity and the key are ignored,
+// e.g. to compare a renewed certificate with the old one
+func SameIdentity(a, b *x509.Certificate) bool {
+	if a.Subject.String() != b.Subject.String() || a.IsCA != b.IsCA {
+		return false
+	}
+
+	var aIPs, bIPs, aURIs, bURIs, aUsages, bUsages []string
+	for _, ip := range a.IPAddresses {
+		aIPs = append(aIPs, ip.String())
+	}
+	for _, ip := range b.IPAddresses {
+		bIPs = append(bIPs, ip.String())
+	}
+	for _, uri := range a.URIs {
+		aURIs = append(aURIs, uri.String())
+	}
+	for _, uri := range b.URIs {
+		bURIs = append(bURIs, uri.String())
+	}
+	for _, u := range a.ExtKeyUsage {
+		aUsages = append(aUsages, fmt.Sprint(int(u)))
+	}
+	for _, u := range b.ExtKeyUsage {
+		bUsages = append(bUsages, fmt.Sprint(int(u)))
+	}
+
+	return sameSet(a.DNSNames, b.DNSNames) &&
+		sameSet(aIPs, bIPs) &&
+		sameSet(a.EmailAddresses, b.EmailAddresses) &&
+		sameSet(aURIs, bURIs) &&
+		sameSet(aUsages, bUsages)
+}
+
+// sameSet reports whether both slices hold the same values regardless of their order
+func sameSet(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	a = append([]string{}, a...)
+	b = append([]string{}, b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
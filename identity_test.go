@@ -0,0 +1,105 @@
+package gcert
+
+import (
+	"crypto/x509"
+	"testing"
+	"time"
+)
+
+func TestSameIdentity(t *testing.T) {
+	src := t.TempDir()
+	err := Generate("test.example.com,www.example.com,10.0.0.1", src,
+		WithP256(),
+		WithDuration(time.Hour),
+		WithOrganization("Example Inc"),
+		WithEmailSAN("admin@example.com"),
+		WithURISAN("spiffe://trust-domain/workload"),
+		WithClientAuth(),
+	)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	old, err := ParsePemCertFile(src + "/cert.pem")
+	if err != nil {
+		t.Fatalf("ParsePemCertFile() error = %v", err)
+	}
+
+	renewedDir := t.TempDir()
+	if err = Renew(src+"/cert.pem", src+"/key.pem", renewedDir, WithP256()); err != nil {
+		t.Fatalf("Renew() error = %v", err)
+	}
+
+	renewed, err := ParsePemCertFile(renewedDir + "/cert.pem")
+	if err != nil {
+		t.Fatalf("ParsePemCertFile() error = %v", err)
+	}
+
+	generate := func(host string, opts ...Option) *x509.Certificate {
+		cert, _, err := GenerateCert(host, append([]Option{
+			WithP256(),
+			WithOrganization("Example Inc"),
+			WithEmailSAN("admin@example.com"),
+			WithURISAN("spiffe://trust-domain/workload"),
+			WithClientAuth(),
+		}, opts...)...)
+		if err != nil {
+			t.Fatalf("GenerateCert() error = %v", err)
+		}
+
+		return cert
+	}
+
+	tests := []struct {
+		name string
+		cert *x509.Certificate
+		want bool
+	}{
+		{
+			name: "renewed",
+			cert: renewed,
+			want: true,
+		},
+		{
+			name: "reordered SANs",
+			cert: generate("10.0.0.1,www.example.com,test.example.com"),
+			want: true,
+		},
+		{
+			name: "changed DNS SAN",
+			cert: generate("test.example.com,api.example.com,10.0.0.1"),
+			want: false,
+		},
+		{
+			name: "changed IP SAN",
+			cert: generate("test.example.com,www.example.com,10.0.0.2"),
+			want: false,
+		},
+		{
+			name: "changed subject",
+			cert: generate("test.example.com,www.example.com,10.0.0.1", WithOrganization("Other Inc")),
+			want: false,
+		},
+		{
+			name: "changed extended key usage",
+			cert: generate("test.example.com,www.example.com,10.0.0.1", WithExtKeyUsage(x509.ExtKeyUsageServerAuth)),
+			want: false,
+		},
+		{
+			name: "CA",
+			cert: generate("test.example.com,www.example.com,10.0.0.1", WithCA()),
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SameIdentity(old, tt.cert); got != tt.want {
+				t.Errorf("SameIdentity() = %v, want %v", got, tt.want)
+			}
+
+			if got := SameIdentity(tt.cert, old); got != tt.want {
+				t.Errorf("SameIdentity() reversed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
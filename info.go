@@ -0,0 +1,131 @@
+package gcert
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// CertInfo is a human-readable summary of a certificate.
+// The serial number is a decimal string so it survives JSON without precision loss.
+type CertInfo struct {
+	Subject        string        `json:"subject"`
+	Issuer         string        `json:"issuer"`
+	SerialNumber   string        `json:"serial_number"`
+	DNSNames       []string      `json:"dns_names,omitempty"`
+	IPAddresses    []net.IP      `json:"ip_addresses,omitempty"`
+	EmailAddresses []string      `json:"email_addresses,omitempty"`
+	URIs           []string      `json:"uris,omitempty"`
+	NotBefore      time.Time     `json:"not_before"`
+	NotAfter       time.Time     `json:"not_after"`
+	IsCA           bool          `json:"is_ca"`
+	KeyUsage       x509.KeyUsage `json:"key_usage"`
+	Fingerprint    string        `json:"fingerprint"`
+}
+
+// keyUsageNames are the names of the key usage bits as printed by openssl
+var keyUsageNames = []struct {
+	usage x509.KeyUsage
+	name  string
+}{
+	{x509.KeyUsageDigitalSignature, "Digital Signature"},
+	{x509.KeyUsageContentCommitment, "Non Repudiation"},
+	{x509.KeyUsageKeyEncipherment, "Key Encipherment"},
+	{x509.KeyUsageDataEncipherment, "Data Encipherment"},
+	{x509.KeyUsageKeyAgreement, "Key Agreement"},
+	{x509.KeyUsageCertSign, "Certificate Sign"},
+	{x509.KeyUsageCRLSign, "CRL Sign"},
+	{x509.KeyUsageEncipherOnly, "Encipher Only"},
+	{x509.KeyUsageDecipherOnly, "Decipher Only"},
+}
+
+// Info returns a summary of the given pem certificate file
+func Info(path string) (*CertInfo, error) {
+	cert, err := ParsePemCertFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return newCertInfo(cert), nil
+}
+
+// InfoJSON returns the summary of the given pem certificate file as JSON
+func InfoJSON(path string) ([]byte, error) {
+	info, err := Info(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(info)
+}
+
+// newCertInfo builds the summary of the certificate
+func newCertInfo(cert *x509.Certificate) *CertInfo {
+	info := &CertInfo{
+		Subject:        cert.Subject.String(),
+		Issuer:         cert.Issuer.String(),
+		SerialNumber:   cert.SerialNumber.String(),
+		DNSNames:       cert.DNSNames,
+		IPAddresses:    cert.IPAddresses,
+		EmailAddresses: cert.EmailAddresses,
+		NotBefore:      cert.NotBefore,
+		NotAfter:       cert.NotAfter,
+		IsCA:           cert.IsCA,
+		KeyUsage:       cert.KeyUsage,
+		Fingerprint:    Fingerprint(cert),
+	}
+
+	for _, uri := range cert.URIs {
+		info.URIs = append(info.URIs, uri.String())
+	}
+
+	return info
+}
+
+// String formats the summary similar to openssl x509 -text
+func (i *CertInfo) String() string {
+	const dateLayout = "Jan _2 15:04:05 2006 GMT"
+
+	var sans []string
+	for _, name := range i.DNSNames {
+		sans = append(sans, "DNS:"+name)
+	}
+	for _, ip := range i.IPAddresses {
+		sans = append(sans, "IP Address:"+ip.String())
+	}
+	for _, email := range i.EmailAddresses {
+		sans = append(sans, "email:"+email)
+	}
+	for _, uri := range i.URIs {
+		sans = append(sans, "URI:"+uri)
+	}
+
+	var usages []string
+	for _, ku := range keyUsageNames {
+		if i.KeyUsage&ku.usage != 0 {
+			usages = append(usages, ku.name)
+		}
+	}
+
+	isCA := "FALSE"
+	if i.IsCA {
+		isCA = "TRUE"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Serial Number: %s\n", i.SerialNumber)
+	fmt.Fprintf(&b, "Issuer: %s\n", i.Issuer)
+	fmt.Fprintf(&b, "Validity\n")
+	fmt.Fprintf(&b, "    Not Before: %s\n", i.NotBefore.UTC().Format(dateLayout))
+	fmt.Fprintf(&b, "    Not After : %s\n", i.NotAfter.UTC().Format(dateLayout))
+	fmt.Fprintf(&b, "Subject: %s\n", i.Subject)
+	fmt.Fprintf(&b, "X509v3 Subject Alternative Name: %s\n", strings.Join(sans, ", "))
+	fmt.Fprintf(&b, "X509v3 Basic Constraints: CA:%s\n", isCA)
+	fmt.Fprintf(&b, "X509v3 Key Usage: %s\n", strings.Join(usages, ", "))
+	fmt.Fprintf(&b, "SHA256 Fingerprint=%s\n", colonHex(i.Fingerprint))
+
+	return b.String()
+}
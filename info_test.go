@@ -0,0 +1,123 @@
+package gcert
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"math/big"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInfo(t *testing.T) {
+	dest := t.TempDir()
+	err := Generate("test.example.com,127.0.0.1", dest,
+		WithP256(),
+		WithCA(),
+		WithEmailSAN("admin@example.com"),
+		WithSerialNumber(big.NewInt(4242)),
+		WithStartDate("2030-01-02T15:04:05Z"),
+		WithDuration(time.Hour),
+	)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	info, err := Info(dest + "/cert.pem")
+	if err != nil {
+		t.Fatalf("Info() error = %v", err)
+	}
+
+	fingerprint, err := FingerprintFile(dest + "/cert.pem")
+	if err != nil {
+		t.Fatalf("FingerprintFile() error = %v", err)
+	}
+
+	want := &CertInfo{
+		Subject:        "O=Acme Co",
+		Issuer:         "O=Acme Co",
+		SerialNumber:   "4242",
+		DNSNames:       []string{"test.example.com"},
+		IPAddresses:    []net.IP{net.ParseIP("127.0.0.1").To4()},
+		EmailAddresses: []string{"admin@example.com"},
+		NotBefore:      time.Date(2030, time.January, 2, 15, 4, 5, 0, time.UTC),
+		NotAfter:       time.Date(2030, time.January, 2, 16, 4, 5, 0, time.UTC),
+		IsCA:           true,
+		KeyUsage:       x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		Fingerprint:    fingerprint,
+	}
+
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("Info() = %+v, want %+v", info, want)
+	}
+
+	text := info.String()
+	for _, line := range []string{
+		"Serial Number: 4242",
+		"Not Before: Jan  2 15:04:05 2030 GMT",
+		"Not After : Jan  2 16:04:05 2030 GMT",
+		"X509v3 Subject Alternative Name: DNS:test.example.com, IP Address:127.0.0.1, email:admin@example.com",
+		"X509v3 Basic Constraints: CA:TRUE",
+		"X509v3 Key Usage: Digital Signature, Certificate Sign, CRL Sign",
+		"SHA256 Fingerprint=" + colonHex(fingerprint),
+	} {
+		if !strings.Contains(text, line) {
+			t.Errorf("String() = %q, want it to contain %q", text, line)
+		}
+	}
+
+	if _, err = Info(dest + "/missing.pem"); err == nil {
+		t.Errorf("Info() expected error for missing file")
+	}
+}
+
+func TestInfoJSON(t *testing.T) {
+	dest := t.TempDir()
+	serial, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	err := Generate("test.example.com,127.0.0.1", dest,
+		WithP256(),
+		WithSerialNumber(serial),
+		WithStartDate("2030-01-02T15:04:05Z"),
+		WithDuration(time.Hour),
+	)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	data, err := InfoJSON(dest + "/cert.pem")
+	if err != nil {
+		t.Fatalf("InfoJSON() error = %v", err)
+	}
+
+	var got map[string]any
+	if err = json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	fingerprint, err := FingerprintFile(dest + "/cert.pem")
+	if err != nil {
+		t.Fatalf("FingerprintFile() error = %v", err)
+	}
+
+	want := map[string]any{
+		"subject":       "O=Acme Co",
+		"serial_number": "123456789012345678901234567890",
+		"dns_names":     []any{"test.example.com"},
+		"ip_addresses":  []any{"127.0.0.1"},
+		"not_before":    "2030-01-02T15:04:05Z",
+		"not_after":     "2030-01-02T16:04:05Z",
+		"fingerprint":   fingerprint,
+	}
+
+	for key, value := range want {
+		if !reflect.DeepEqual(got[key], value) {
+			t.Errorf("InfoJSON() %s = %v, want %v", key, got[key], value)
+		}
+	}
+
+	if _, err = InfoJSON(dest + "/missing.pem"); err == nil {
+		t.Errorf("InfoJSON() expected error for missing file")
+	}
+}
@@ -1,6 +1,13 @@
 package gcert
 
 import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"io"
+	"math/big"
+	"os"
 	"time"
 )
 
@@ -11,44 +18,302 @@ const (
 	CurveP521 = "P521"
 )
 
+// oidEmailAddress is the PKCS#9 emailAddress attribute of a subject
+var oidEmailAddress = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 1}
+
+// minRSABits is the smallest RSA key size accepted without WithInsecureRSABits
+const minRSABits = 2048
+
 type Option func(*options)
 
+// VerifyOption configures the certificate verification of VerifyWithOptions
+type VerifyOption func(*x509.VerifyOptions)
+
 type options struct {
-	parentCert   string
-	parentKey    string
-	certFileName string
-	keyFileName  string
-	validFrom    string
-	validFor     time.Duration
-	rsaBits      int
-	ecdsaCurve   string
-	ed25519Key   bool
-	isCA         bool
+	parentCert          string
+	parentKey           string
+	signerCert          *x509.Certificate // already loaded parent, takes precedence over parentCert
+	signerKey           any
+	certFileName        string
+	keyFileName         string
+	csrFileName         string
+	crlFileName         string
+	combinedFileName    string
+	provenanceFile      string
+	subject             pkix.Name
+	issuer              *pkix.Name
+	emails              []string
+	uris                []string
+	ipRanges            []string
+	reverseDNS          bool
+	keyUsage            x509.KeyUsage
+	extKeyUsage         []x509.ExtKeyUsage
+	criticalExtKeyUsage bool
+	extraExtensions     []pkix.Extension
+	policyOIDs          []asn1.ObjectIdentifier
+	validFrom           string
+	validFor            time.Duration
+	notBefore           time.Time
+	notAfter            time.Time
+	clockSkew           time.Duration
+	maxValidity         time.Duration
+	rsaBits             int
+	insecureRSA         bool
+	strict              bool
+	ecdsaCurve          string
+	ed25519Key          bool
+	isCA                bool
+	noBasicConstraints  bool
+	pathLen             int
+	permittedDNS        []string
+	excludedDNS         []string
+	passphrase          []byte
+	pkcs1               bool
+	sec1                bool
+	existingKey         string
+	noKeyFile           bool
+	reuseKey            bool
+	serialNumber        *big.Int
+	customSerial        bool
+	serialSource        SerialSource
+	derOutput           bool
+	pemHeaders          map[string]string
+	crlf                bool
+	noClobber           bool
+	uniqueNames         bool
+	createDir           bool
+	certMode            os.FileMode
+	keyMode             os.FileMode
+	rand                io.Reader
+	signatureAlgorithm  x509.SignatureAlgorithm
 }
 
 func initOptions() options {
 	return options{
 		certFileName: "cert.pem",
 		keyFileName:  "key.pem",
+		csrFileName:  "csr.pem",
+		crlFileName:  "crl.pem",
 		validFor:     365 * 24 * time.Hour,
 		rsaBits:      2048,
+		certMode:     0644,
+		keyMode:      0600,
+		rand:         rand.Reader,
+		pathLen:      -1,
+		extKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		subject: pkix.Name{
+			Organization: []string{"Acme Co"},
+		},
 	}
 }
 
-// WithKeyFileName the generated key file name (default key.pem)
+// WithKeyFileName the generated key file name (default key.pem), the {host}, {serial} and {date}
+// placeholders are expanded (e.g. {host}-key.pem)
 func WithKeyFileName(keyFileName string) Option {
 	return func(o *options) {
 		o.keyFileName = keyFileName
 	}
 }
 
-// WithCertFileName the generated cert file name (default cert.pem)
+// WithCertFileName the generated cert file name (default cert.pem), the {host}, {serial} and {date}
+// placeholders are expanded (e.g. {host}-cert.pem)
 func WithCertFileName(certFileName string) Option {
 	return func(o *options) {
 		o.certFileName = certFileName
 	}
 }
 
+// WithCSRFileName the generated certificate signing request file name (default csr.pem)
+func WithCSRFileName(csrFileName string) Option {
+	return func(o *options) {
+		o.csrFileName = csrFileName
+	}
+}
+
+// WithCRLFileName the generated certificate revocation list file name (default crl.pem)
+func WithCRLFileName(crlFileName string) Option {
+	return func(o *options) {
+		o.crlFileName = crlFileName
+	}
+}
+
+// WithProvenanceFile writes a JSON audit record of the generated certificate (hosts, key type,
+// validity, serial number and fingerprint) to path, only after the certificate is written
+func WithProvenanceFile(path string) Option {
+	return func(o *options) {
+		o.provenanceFile = path
+	}
+}
+
+// WithSubject replaces the whole subject of the certificate (default O=Acme Co)
+func WithSubject(subject pkix.Name) Option {
+	return func(o *options) {
+		o.subject = subject
+	}
+}
+
+// WithIssuer overrides the issuer name of a self-signed certificate (default is its subject),
+// e.g. to test cross-signing. It is ignored with WithSignByParent, the parent is the issuer
+func WithIssuer(name pkix.Name) Option {
+	return func(o *options) {
+		o.issuer = &name
+	}
+}
+
+// WithOrganization subject organization of the certificate (default Acme Co)
+func WithOrganization(orgs ...string) Option {
+	return func(o *options) {
+		o.subject.Organization = orgs
+	}
+}
+
+// WithCommonName subject common name of the certificate
+func WithCommonName(cn string) Option {
+	return func(o *options) {
+		o.subject.CommonName = cn
+	}
+}
+
+// WithOrganizationalUnit subject organizational units of the certificate
+func WithOrganizationalUnit(ous ...string) Option {
+	return func(o *options) {
+		o.subject.OrganizationalUnit = ous
+	}
+}
+
+// WithCountry subject countries of the certificate (e.g. US)
+func WithCountry(countries ...string) Option {
+	return func(o *options) {
+		o.subject.Country = countries
+	}
+}
+
+// WithProvince subject states or provinces of the certificate
+func WithProvince(provinces ...string) Option {
+	return func(o *options) {
+		o.subject.Province = provinces
+	}
+}
+
+// WithLocality subject localities (cities) of the certificate
+func WithLocality(localities ...string) Option {
+	return func(o *options) {
+		o.subject.Locality = localities
+	}
+}
+
+// WithSubjectEmail legacy emailAddress attribute in the subject of the certificate,
+// use WithEmailSAN for the subject alternative name
+func WithSubjectEmail(addr string) Option {
+	return func(o *options) {
+		o.subject.ExtraNames = append(o.subject.ExtraNames, pkix.AttributeTypeAndValue{
+			Type:  oidEmailAddress,
+			Value: asn1.RawValue{Tag: asn1.TagIA5String, Bytes: []byte(addr)},
+		})
+	}
+}
+
+// WithSubjectSerialNumber subject serial number attribute of the certificate (e.g. a device identifier),
+// unrelated to the certificate serial number of WithSerialNumber
+func WithSubjectSerialNumber(serial string) Option {
+	return func(o *options) {
+		o.subject.SerialNumber = serial
+	}
+}
+
+// WithEmailSAN email addresses to add to the certificate subject alternative names
+func WithEmailSAN(emails ...string) Option {
+	return func(o *options) {
+		o.emails = append(o.emails, emails...)
+	}
+}
+
+// WithURISAN URIs to add to the certificate subject alternative names (e.g. spiffe://trust-domain/workload)
+func WithURISAN(uris ...string) Option {
+	return func(o *options) {
+		o.uris = append(o.uris, uris...)
+	}
+}
+
+// WithIPRange adds every address of the CIDR (e.g. 10.0.0.0/30) to the certificate IP SANs.
+// X.509 has no range SAN, so the range is limited to 256 addresses
+func WithIPRange(cidr string) Option {
+	return func(o *options) {
+		o.ipRanges = append(o.ipRanges, cidr)
+	}
+}
+
+// WithReverseDNS adds the reverse DNS (PTR) names of the IP hosts, including the addresses
+// of WithIPRange, to the DNS names. The lookups are best-effort and run concurrently
+// within a few seconds overall, IPs failing or not answered in time are skipped
+func WithReverseDNS() Option {
+	return func(o *options) {
+		o.reverseDNS = true
+	}
+}
+
+// WithKeyUsage replaces the default key usage bits (DigitalSignature, plus KeyEncipherment for RSA keys).
+// WithCA still adds CertSign and CRLSign on top of the given usage
+func WithKeyUsage(usage x509.KeyUsage) Option {
+	return func(o *options) {
+		o.keyUsage = usage
+	}
+}
+
+// WithExtKeyUsage replaces the extended key usages of the certificate (default server auth)
+func WithExtKeyUsage(usages ...x509.ExtKeyUsage) Option {
+	return func(o *options) {
+		o.extKeyUsage = usages
+	}
+}
+
+// WithCriticalExtKeyUsage marks the extended key usage extension as critical
+func WithCriticalExtKeyUsage() Option {
+	return func(o *options) {
+		o.criticalExtKeyUsage = true
+	}
+}
+
+// WithPolicyOID adds certificate policy identifiers (e.g. the CP/CPS OID of the PKI) to the
+// certificate policies extension
+func WithPolicyOID(oids ...asn1.ObjectIdentifier) Option {
+	return func(o *options) {
+		o.policyOIDs = append(o.policyOIDs, oids...)
+	}
+}
+
+// WithExtraExtension adds custom extensions to the certificate. An extension replaces the one
+// gcert would generate with the same object identifier
+func WithExtraExtension(exts ...pkix.Extension) Option {
+	return func(o *options) {
+		o.extraExtensions = append(o.extraExtensions, exts...)
+	}
+}
+
+// WithMustStaple adds the TLS Feature extension with status_request (OCSP Must-Staple) so
+// clients require a stapled OCSP response
+func WithMustStaple() Option {
+	return func(o *options) {
+		o.extraExtensions = append(o.extraExtensions, pkix.Extension{Id: oidExtensionTLSFeature, Value: mustStapleValue})
+	}
+}
+
+// WithClientAuth adds client auth to the extended key usages so the certificate can be used for mTLS clients
+func WithClientAuth() Option {
+	return func(o *options) {
+		o.extKeyUsage = append(o.extKeyUsage, x509.ExtKeyUsageClientAuth)
+	}
+}
+
+// WithCodeSigning makes a signing only certificate: code signing is the only extended key usage
+// (no server auth) and digital signature the only key usage
+func WithCodeSigning() Option {
+	return func(o *options) {
+		o.extKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning}
+		o.keyUsage = x509.KeyUsageDigitalSignature
+	}
+}
+
 // WithSignByParent signs the generated certificate as parent (path of cert and key file of the signer)
 func WithSignByParent(parentCertPath, parentKeyPath string) Option {
 	return func(o *options) {
@@ -57,13 +322,20 @@ func WithSignByParent(parentCertPath, parentKeyPath string) Option {
 	}
 }
 
-// WithStartDate creation date formatted as Jan 1 15:04:05 2011
+// WithStartDate creation date formatted as Jan 1 15:04:05 2011 or RFC3339 (2011-01-01T15:04:05Z)
 func WithStartDate(startDate string) Option {
 	return func(o *options) {
 		o.validFrom = startDate
 	}
 }
 
+// WithValidFrom creation date of the certificate, takes precedence over WithStartDate
+func WithValidFrom(notBefore time.Time) Option {
+	return func(o *options) {
+		o.notBefore = notBefore
+	}
+}
+
 // WithDuration duration that certificate is valid for
 func WithDuration(duration time.Duration) Option {
 	return func(o *options) {
@@ -71,20 +343,99 @@ func WithDuration(duration time.Duration) Option {
 	}
 }
 
-// WithCA cert should be its own Certificate Authority
+// WithNotAfter expiry date of the certificate, takes precedence over WithDuration
+func WithNotAfter(notAfter time.Time) Option {
+	return func(o *options) {
+		o.notAfter = notAfter
+	}
+}
+
+// WithStrict rejects weak crypto: RSA keys below 2048 bits (even with WithInsecureRSABits),
+// the P224 curve, SHA-1 signatures and non-CA certificates valid for longer than 398 days
+// (or the WithMaxValidity cap), also for the request key and CA key of SignCSR
+func WithStrict() Option {
+	return func(o *options) {
+		o.strict = true
+	}
+}
+
+// WithMaxValidity rejects certificates valid for longer than d (see WithDuration and WithNotAfter),
+// guarding against a typo turning 90 days into 90 years. The WithClockSkew backdate is not counted
+func WithMaxValidity(d time.Duration) Option {
+	return func(o *options) {
+		o.maxValidity = d
+	}
+}
+
+// WithoutBasicConstraints omits the basic constraints extension (CA:FALSE) from non-CA certificates.
+// CA certificates always have it
+func WithoutBasicConstraints() Option {
+	return func(o *options) {
+		o.noBasicConstraints = true
+	}
+}
+
+// WithClockSkew backdates the creation date by d so peers with a slightly skewed clock
+// accept the certificate right away. The expiry date is not changed
+func WithClockSkew(d time.Duration) Option {
+	return func(o *options) {
+		o.clockSkew = d
+	}
+}
+
+// WithCA cert should be its own Certificate Authority, with the CertSign and CRLSign key usages
 func WithCA() Option {
 	return func(o *options) {
 		o.isCA = true
 	}
 }
 
-// WithRSABits size of RSA key to generate. Ignored if --ecdsa-curve is set
+// WithIntermediateCA cert should be an intermediate Certificate Authority signed by the parent CA,
+// same as WithCA with WithSignByParent
+func WithIntermediateCA(parentCertPath, parentKeyPath string) Option {
+	return func(o *options) {
+		o.isCA = true
+		o.parentCert = parentCertPath
+		o.parentKey = parentKeyPath
+	}
+}
+
+// WithPathLen maximum number of intermediate CAs allowed below this CA (requires WithCA)
+func WithPathLen(n int) Option {
+	return func(o *options) {
+		o.pathLen = n
+	}
+}
+
+// WithPermittedDNSDomains restricts the DNS names this CA may issue certificates for (requires WithCA)
+func WithPermittedDNSDomains(domains ...string) Option {
+	return func(o *options) {
+		o.permittedDNS = append(o.permittedDNS, domains...)
+	}
+}
+
+// WithExcludedDNSDomains excludes DNS names this CA may not issue certificates for (requires WithCA)
+func WithExcludedDNSDomains(domains ...string) Option {
+	return func(o *options) {
+		o.excludedDNS = append(o.excludedDNS, domains...)
+	}
+}
+
+// WithRSABits size of RSA key to generate, at least 2048. Ignored if --ecdsa-curve is set
 func WithRSABits(bits int) Option {
 	return func(o *options) {
 		o.rsaBits = bits
 	}
 }
 
+// WithInsecureRSABits size of RSA key to generate without enforcing the 2048 bits minimum
+func WithInsecureRSABits(bits int) Option {
+	return func(o *options) {
+		o.rsaBits = bits
+		o.insecureRSA = true
+	}
+}
+
 // WithP224 ECDSA P224 curve to use to generate a key
 func WithP224() Option {
 	return func(o *options) {
@@ -113,9 +464,162 @@ func WithP521() Option {
 	}
 }
 
+// WithCurve ECDSA curve by name (CurveP224, CurveP256, CurveP384 or CurveP521) to use to generate a key.
+// Unknown names make Generate fail with ErrUnrecognizedCurve, an empty name keeps the default RSA key
+func WithCurve(name string) Option {
+	return func(o *options) {
+		o.ecdsaCurve = name
+	}
+}
+
 // WithED25519 generate an Ed25519 key
 func WithED25519() Option {
 	return func(o *options) {
 		o.ed25519Key = true
 	}
 }
+
+// WithKeyPassphrase encrypts the generated private key with the given passphrase (PKCS#8 PBES2, AES-256-CBC)
+func WithKeyPassphrase(pass []byte) Option {
+	return func(o *options) {
+		o.passphrase = pass
+	}
+}
+
+// WithPKCS1 writes RSA private keys in PKCS#1 (RSA PRIVATE KEY) format instead of PKCS#8
+func WithPKCS1() Option {
+	return func(o *options) {
+		o.pkcs1 = true
+	}
+}
+
+// WithSEC1 writes ECDSA private keys in SEC1 (EC PRIVATE KEY) format instead of PKCS#8
+func WithSEC1() Option {
+	return func(o *options) {
+		o.sec1 = true
+	}
+}
+
+// WithExistingKey signs the certificate with the private key at keyPath instead of generating a new one.
+// Key type options (WithRSABits, WithP256, WithED25519, ...) are ignored
+func WithExistingKey(keyPath string) Option {
+	return func(o *options) {
+		o.existingKey = keyPath
+	}
+}
+
+// WithoutKeyFile writes only the certificate file, the key must already exist (see WithExistingKey)
+func WithoutKeyFile() Option {
+	return func(o *options) {
+		o.noKeyFile = true
+	}
+}
+
+// WithReuseKey keeps the private key of the renewed certificate instead of generating a new one (see Renew)
+func WithReuseKey() Option {
+	return func(o *options) {
+		o.reuseKey = true
+	}
+}
+
+// WithSerialNumber uses the given positive serial number instead of a random one
+func WithSerialNumber(serial *big.Int) Option {
+	return func(o *options) {
+		o.serialNumber = serial
+		o.customSerial = true
+	}
+}
+
+// WithSerialSource takes the serial number from s (e.g. NewFileSerialSource), WithSerialNumber takes precedence.
+// The serial number is drawn once all other checks passed, Validate does not draw one
+func WithSerialSource(s SerialSource) Option {
+	return func(o *options) {
+		o.serialSource = s
+	}
+}
+
+// WithDEROutput writes the certificate and private key as raw DER (cert.der and key.der) instead of PEM
+func WithDEROutput() Option {
+	return func(o *options) {
+		o.derOutput = true
+	}
+}
+
+// WithPEMHeaders adds the headers (e.g. "Comment") to the pem block of the certificate
+func WithPEMHeaders(headers map[string]string) Option {
+	return func(o *options) {
+		o.pemHeaders = make(map[string]string, len(headers))
+		for k, v := range headers {
+			o.pemHeaders[k] = v
+		}
+	}
+}
+
+// WithCRLF writes the pem certificate and key files with Windows (CRLF) line endings
+func WithCRLF() Option {
+	return func(o *options) {
+		o.crlf = true
+	}
+}
+
+// WithNoClobber returns an error instead of overwriting existing certificate or key files
+func WithNoClobber() Option {
+	return func(o *options) {
+		o.noClobber = true
+	}
+}
+
+// WithCreateDir creates the destination directory and its parents (0755) if missing
+func WithCreateDir() Option {
+	return func(o *options) {
+		o.createDir = true
+	}
+}
+
+// WithUniqueNames appends the hex serial number to the generated file names (cert-<serial>.pem and
+// key-<serial>.pem), so concurrent generations into the same directory do not overwrite each other
+func WithUniqueNames() Option {
+	return func(o *options) {
+		o.uniqueNames = true
+	}
+}
+
+// WithFileMode the permissions of the written certificate and key files (default 0644 and 0600)
+func WithFileMode(certMode, keyMode os.FileMode) Option {
+	return func(o *options) {
+		o.certMode = certMode
+		o.keyMode = keyMode
+	}
+}
+
+// WithRand source of randomness for key, serial number and certificate generation (default crypto/rand).
+// Go may ignore it for RSA and ECDSA key generation, only Ed25519 keys are fully deterministic.
+func WithRand(r io.Reader) Option {
+	return func(o *options) {
+		o.rand = r
+	}
+}
+
+// WithSignatureAlgorithm signature algorithm of the certificate, e.g. x509.SHA256WithRSAPSS.
+// It must match the signer key type, by default it is chosen based on the key
+func WithSignatureAlgorithm(alg x509.SignatureAlgorithm) Option {
+	return func(o *options) {
+		o.signatureAlgorithm = alg
+	}
+}
+
+// WithVerifyTime verifies the certificate at the given time instead of now
+func WithVerifyTime(t time.Time) VerifyOption {
+	return func(o *x509.VerifyOptions) {
+		o.CurrentTime = t
+	}
+}
+
+// WithCombinedOutput writes the private key followed by the certificate into a single
+// file with the given name instead of separate cert and key files. The file gets the
+// key file permissions (default 0600)
+func WithCombinedOutput(fileName string) Option {
+	return func(o *options) {
+		o.combinedFileName = fileName
+	}
+}
@@ -0,0 +1,446 @@
+package gcert
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"hash"
+	"os"
+	"unicode/utf16"
+)
+
+// PKCS#12 (RFC 7292) bundles using the modern profile also produced by OpenSSL 3:
+// certificates are stored unencrypted, the private key is stored as a PKCS#8
+// shrouded key bag (PBES2, AES-256-CBC) and the bundle is integrity protected
+// with an HMAC-SHA256 MAC.
+// Parsing also accepts certificates in PBES2 encrypted content, the OpenSSL 3 default,
+// and HMAC-SHA1 MACs. The legacy RC2 and 3DES PBEs (openssl pkcs12 -legacy, OpenSSL 1.x
+// and older Windows exports) are not supported. gcert has no third-party dependencies,
+// so the KDFs are implemented here and checked against known-answer vectors.
+
+const pkcs12MacIterations = 2048
+
+var (
+	oidDataContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidEncryptedDataType = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 6}
+	oidCertBag           = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 3}
+	oidKeyBag            = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 1}
+	oidShroudedKeyBag    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 2}
+	oidX509Certificate   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 22, 1}
+	oidLocalKeyID        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 21}
+	oidSHA256            = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA1              = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	errUnsupportedPKCS12 = errors.New("unsupported PKCS#12 content")
+)
+
+type pfxPdu struct {
+	Version  int
+	AuthSafe contentInfo
+	MacData  macData `asn1:"optional"`
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"tag:0,explicit,optional"`
+}
+
+type encryptedData struct {
+	Version              int
+	EncryptedContentInfo encryptedContentInfo
+}
+
+type encryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           []byte `asn1:"tag:0,optional"`
+}
+
+type macData struct {
+	Mac        digestInfo
+	MacSalt    []byte
+	Iterations int `asn1:"optional,default:1"`
+}
+
+type digestInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	Digest    []byte
+}
+
+type safeBag struct {
+	ID         asn1.ObjectIdentifier
+	Value      asn1.RawValue     `asn1:"tag:0,explicit"`
+	Attributes []pkcs12Attribute `asn1:"set,optional"`
+}
+
+type pkcs12Attribute struct {
+	ID    asn1.ObjectIdentifier
+	Value asn1.RawValue
+}
+
+type certBag struct {
+	ID   asn1.ObjectIdentifier
+	Data []byte `asn1:"tag:0,explicit"`
+}
+
+// ExportPKCS12 packages the certificate at certPath (followed by its optional chain in
+// the same file) and the private key at keyPath into a PKCS#12 (.p12/.pfx) bundle
+// written to the dest file. password may be empty for tools that require it.
+func ExportPKCS12(certPath, keyPath, dest, password string) error {
+	certs, err := ParsePemCertChainFile(certPath)
+	if err != nil {
+		return err
+	}
+
+	priv, err := ParsePemKeyFile(keyPath)
+	if err != nil {
+		return err
+	}
+
+	if !matchesPublicKey(priv, certs[0].PublicKey) {
+		return fmt.Errorf("%w: %s and %s", ErrKeyMismatch, keyPath, certPath)
+	}
+
+	pfx, err := encodePKCS12(priv, certs, password)
+	if err != nil {
+		return err
+	}
+
+	return writeFile(dest, pfx, 0600, false)
+}
+
+// ParsePKCS12File parses the given PKCS#12 bundle created by ExportPKCS12 or by
+// OpenSSL 3 with its default (PBES2, AES-CBC) encryption and returns the certificate,
+// its private key and the remaining chain certificates. Bundles using the legacy RC2
+// or 3DES encryption return an unsupported encryption algorithm error
+func ParsePKCS12File(path, password string) (*x509.Certificate, any, []*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	return decodePKCS12(data, password)
+}
+
+func encodePKCS12(priv any, certs []*x509.Certificate, password string) ([]byte, error) {
+	localKeyID := sha1.Sum(certs[0].Raw)
+	keyIDAttr, err := localKeyIDAttribute(localKeyID[:])
+	if err != nil {
+		return nil, err
+	}
+
+	var certBags []safeBag
+	for i, cert := range certs {
+		bag, err := asn1.Marshal(certBag{ID: oidX509Certificate, Data: cert.Raw})
+		if err != nil {
+			return nil, err
+		}
+
+		sb := safeBag{ID: oidCertBag, Value: explicitContent(bag)}
+		if i == 0 {
+			sb.Attributes = []pkcs12Attribute{keyIDAttr}
+		}
+		certBags = append(certBags, sb)
+	}
+
+	privBytes, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal private key: %w", err)
+	}
+
+	encrypted, err := encryptPKCS8(privBytes, []byte(password))
+	if err != nil {
+		return nil, fmt.Errorf("unable to encrypt private key: %w", err)
+	}
+
+	keyBags := []safeBag{{
+		ID:         oidShroudedKeyBag,
+		Value:      explicitContent(encrypted),
+		Attributes: []pkcs12Attribute{keyIDAttr},
+	}}
+
+	var authSafe []contentInfo
+	for _, bags := range [][]safeBag{certBags, keyBags} {
+		ci, err := dataContentInfo(bags)
+		if err != nil {
+			return nil, err
+		}
+		authSafe = append(authSafe, ci)
+	}
+
+	authSafeBytes, err := asn1.Marshal(authSafe)
+	if err != nil {
+		return nil, err
+	}
+
+	authSafeContent, err := asn1.Marshal(authSafeBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, 8)
+	if _, err = rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	mac := pkcs12MAC(sha256.New, authSafeBytes, salt, password, pkcs12MacIterations)
+
+	return asn1.Marshal(pfxPdu{
+		Version: 3,
+		AuthSafe: contentInfo{
+			ContentType: oidDataContentType,
+			Content:     explicitContent(authSafeContent),
+		},
+		MacData: macData{
+			Mac: digestInfo{
+				Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA256, Parameters: asn1.NullRawValue},
+				Digest:    mac,
+			},
+			MacSalt:    salt,
+			Iterations: pkcs12MacIterations,
+		},
+	})
+}
+
+func decodePKCS12(data []byte, password string) (*x509.Certificate, any, []*x509.Certificate, error) {
+	var pfx pfxPdu
+	if _, err := asn1.Unmarshal(data, &pfx); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to parse PKCS#12 data: %w", err)
+	}
+
+	if pfx.Version != 3 || !pfx.AuthSafe.ContentType.Equal(oidDataContentType) {
+		return nil, nil, nil, errUnsupportedPKCS12
+	}
+
+	var authSafeBytes []byte
+	if _, err := asn1.Unmarshal(pfx.AuthSafe.Content.Bytes, &authSafeBytes); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to parse PKCS#12 content: %w", err)
+	}
+
+	var h func() hash.Hash
+	switch {
+	case pfx.MacData.Mac.Algorithm.Algorithm.Equal(oidSHA256):
+		h = sha256.New
+	case pfx.MacData.Mac.Algorithm.Algorithm.Equal(oidSHA1):
+		h = sha1.New
+	default:
+		return nil, nil, nil, fmt.Errorf("unsupported PKCS#12 MAC algorithm: %v", pfx.MacData.Mac.Algorithm.Algorithm)
+	}
+
+	if err := checkIterations(pfx.MacData.Iterations); err != nil {
+		return nil, nil, nil, err
+	}
+
+	mac := pkcs12MAC(h, authSafeBytes, pfx.MacData.MacSalt, password, pfx.MacData.Iterations)
+	if !hmac.Equal(mac, pfx.MacData.Mac.Digest) {
+		return nil, nil, nil, errors.New("failed to verify PKCS#12 MAC: incorrect password")
+	}
+
+	var authSafe []contentInfo
+	if _, err := asn1.Unmarshal(authSafeBytes, &authSafe); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to parse PKCS#12 content: %w", err)
+	}
+
+	var certs []*x509.Certificate
+	var priv any
+	for _, ci := range authSafe {
+		bagsBytes, err := safeContents(ci, password)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		var bags []safeBag
+		if _, err := asn1.Unmarshal(bagsBytes, &bags); err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to parse PKCS#12 bags: %w", err)
+		}
+
+		for _, bag := range bags {
+			var err error
+			switch {
+			case bag.ID.Equal(oidCertBag):
+				var cb certBag
+				if _, err = asn1.Unmarshal(bag.Value.Bytes, &cb); err != nil {
+					return nil, nil, nil, fmt.Errorf("failed to parse PKCS#12 certificate bag: %w", err)
+				}
+
+				cert, err := x509.ParseCertificate(cb.Data)
+				if err != nil {
+					return nil, nil, nil, fmt.Errorf("failed to parse DER data: %w", err)
+				}
+				certs = append(certs, cert)
+			case bag.ID.Equal(oidShroudedKeyBag):
+				decrypted, err := decryptPKCS8(bag.Value.Bytes, []byte(password))
+				if err != nil {
+					return nil, nil, nil, err
+				}
+
+				priv, err = x509.ParsePKCS8PrivateKey(decrypted)
+				if err != nil {
+					return nil, nil, nil, fmt.Errorf("failed to parse DER data: %w", err)
+				}
+			case bag.ID.Equal(oidKeyBag):
+				priv, err = x509.ParsePKCS8PrivateKey(bag.Value.Bytes)
+				if err != nil {
+					return nil, nil, nil, fmt.Errorf("failed to parse DER data: %w", err)
+				}
+			}
+		}
+	}
+
+	if len(certs) == 0 || priv == nil {
+		return nil, nil, nil, errors.New("PKCS#12 data does not contain a certificate and private key")
+	}
+
+	// the leaf is the certificate matching the private key, the rest is the chain
+	for i, cert := range certs {
+		if matchesPublicKey(priv, cert.PublicKey) {
+			chain := append(append([]*x509.Certificate{}, certs[:i]...), certs[i+1:]...)
+			return cert, priv, chain, nil
+		}
+	}
+
+	return nil, nil, nil, errors.New("PKCS#12 private key does not match any certificate")
+}
+
+// safeContents returns the DER encoded safe bags of the data or password encrypted ContentInfo
+func safeContents(ci contentInfo, password string) ([]byte, error) {
+	switch {
+	case ci.ContentType.Equal(oidDataContentType):
+		var bagsBytes []byte
+		if _, err := asn1.Unmarshal(ci.Content.Bytes, &bagsBytes); err != nil {
+			return nil, fmt.Errorf("failed to parse PKCS#12 content: %w", err)
+		}
+
+		return bagsBytes, nil
+	case ci.ContentType.Equal(oidEncryptedDataType):
+		var ed encryptedData
+		if _, err := asn1.Unmarshal(ci.Content.Bytes, &ed); err != nil {
+			return nil, fmt.Errorf("failed to parse PKCS#12 encrypted content: %w", err)
+		}
+
+		info := ed.EncryptedContentInfo
+		bagsBytes, err := decryptPBES2(info.ContentEncryptionAlgorithm, info.EncryptedContent, []byte(password))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt PKCS#12 content: %w", err)
+		}
+
+		return bagsBytes, nil
+	default:
+		return nil, errUnsupportedPKCS12
+	}
+}
+
+// dataContentInfo wraps the safe bags into a data ContentInfo
+func dataContentInfo(bags []safeBag) (contentInfo, error) {
+	bagsBytes, err := asn1.Marshal(bags)
+	if err != nil {
+		return contentInfo{}, err
+	}
+
+	content, err := asn1.Marshal(bagsBytes)
+	if err != nil {
+		return contentInfo{}, err
+	}
+
+	return contentInfo{
+		ContentType: oidDataContentType,
+		Content:     explicitContent(content),
+	}, nil
+}
+
+// explicitContent wraps the DER encoded value into an [0] EXPLICIT tag
+func explicitContent(der []byte) asn1.RawValue {
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: der}
+}
+
+func localKeyIDAttribute(id []byte) (pkcs12Attribute, error) {
+	value, err := asn1.Marshal(id)
+	if err != nil {
+		return pkcs12Attribute{}, err
+	}
+
+	return pkcs12Attribute{
+		ID:    oidLocalKeyID,
+		Value: asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: value},
+	}, nil
+}
+
+// pkcs12MAC computes the HMAC of data with a key derived from the password as
+// described in RFC 7292 appendix B
+func pkcs12MAC(h func() hash.Hash, data, salt []byte, password string, iterations int) []byte {
+	key := pkcs12KDF(h, salt, bmpString(password), iterations, 3, h().Size())
+	mac := hmac.New(h, key)
+	mac.Write(data)
+
+	return mac.Sum(nil)
+}
+
+// pkcs12KDF derives size bytes of key material as described in RFC 7292 appendix B.2
+func pkcs12KDF(h func() hash.Hash, salt, password []byte, iterations int, id byte, size int) []byte {
+	hh := h()
+	u := hh.Size()
+	v := hh.BlockSize()
+
+	fill := func(b []byte) []byte {
+		if len(b) == 0 {
+			return nil
+		}
+		out := make([]byte, v*((len(b)+v-1)/v))
+		for i := range out {
+			out[i] = b[i%len(b)]
+		}
+		return out
+	}
+
+	d := make([]byte, v)
+	for i := range d {
+		d[i] = id
+	}
+
+	i := append(fill(salt), fill(password)...)
+
+	var out []byte
+	for len(out) < size {
+		hh.Reset()
+		hh.Write(d)
+		hh.Write(i)
+		a := hh.Sum(nil)
+		for n := 1; n < iterations; n++ {
+			hh.Reset()
+			hh.Write(a)
+			a = hh.Sum(a[:0])
+		}
+		out = append(out, a...)
+
+		b := make([]byte, v)
+		for k := range b {
+			b[k] = a[k%u]
+		}
+
+		for j := 0; j < len(i); j += v {
+			carry := 1
+			for k := v - 1; k >= 0; k-- {
+				sum := int(i[j+k]) + int(b[k]) + carry
+				i[j+k] = byte(sum)
+				carry = sum >> 8
+			}
+		}
+	}
+
+	return out[:size]
+}
+
+// bmpString encodes the password as a null terminated big endian UTF-16 string
+func bmpString(s string) []byte {
+	var out []byte
+	for _, r := range utf16.Encode([]rune(s)) {
+		out = append(out, byte(r>>8), byte(r))
+	}
+
+	return append(out, 0, 0)
+}
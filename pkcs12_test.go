@@ -0,0 +1,220 @@
+package gcert
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestExportPKCS12(t *testing.T) {
+	tests := []struct {
+		name      string
+		password  string
+		withChain bool
+	}{
+		{
+			name:     "with password",
+			password: "secret",
+		},
+		{
+			name:     "with empty password",
+			password: "",
+		},
+		{
+			name:      "with chain",
+			password:  "secret",
+			withChain: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			if err := Generate("cadomain.cert", dest, WithCA(), WithP256(), WithCertFileName("ca_cert.pem"), WithKeyFileName("ca_key.pem")); err != nil {
+				t.Fatalf("Generate() CA error = %v", err)
+			}
+
+			if err := Generate("test.example.com", dest, WithSignByParent(dest+"/ca_cert.pem", dest+"/ca_key.pem")); err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+
+			certPath := dest + "/cert.pem"
+			wantChain := 0
+			if tt.withChain {
+				leaf, err := os.ReadFile(dest + "/cert.pem")
+				if err != nil {
+					t.Fatalf("ReadFile() error = %v", err)
+				}
+				ca, err := os.ReadFile(dest + "/ca_cert.pem")
+				if err != nil {
+					t.Fatalf("ReadFile() error = %v", err)
+				}
+				certPath = dest + "/fullchain.pem"
+				if err = os.WriteFile(certPath, append(leaf, ca...), 0600); err != nil {
+					t.Fatalf("WriteFile() error = %v", err)
+				}
+				wantChain = 1
+			}
+
+			if err := ExportPKCS12(certPath, dest+"/key.pem", dest+"/cert.p12", tt.password); err != nil {
+				t.Fatalf("ExportPKCS12() error = %v", err)
+			}
+
+			cert, priv, chain, err := ParsePKCS12File(dest+"/cert.p12", tt.password)
+			if err != nil {
+				t.Fatalf("ParsePKCS12File() error = %v", err)
+			}
+
+			wantCert, err := ParsePemCertFile(dest + "/cert.pem")
+			if err != nil {
+				t.Fatalf("ParsePemCertFile() error = %v", err)
+			}
+
+			if !cert.Equal(wantCert) {
+				t.Errorf("ParsePKCS12File() certificate does not match")
+			}
+
+			if !matchesPublicKey(priv, wantCert.PublicKey) {
+				t.Errorf("ParsePKCS12File() private key does not match certificate")
+			}
+
+			if len(chain) != wantChain {
+				t.Errorf("ParsePKCS12File() chain length = %d, want %d", len(chain), wantChain)
+			}
+
+			if _, _, _, err = ParsePKCS12File(dest+"/cert.p12", tt.password+"wrong"); err == nil {
+				t.Errorf("ParsePKCS12File() expected error for wrong password")
+			}
+		})
+	}
+}
+
+func TestExportPKCS12MismatchedKey(t *testing.T) {
+	dest := t.TempDir()
+	if err := Generate("test.example.com", dest, WithP256()); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if err := Generate("other.example.com", dest, WithP256(), WithCertFileName("other_cert.pem"), WithKeyFileName("other_key.pem")); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if err := ExportPKCS12(dest+"/cert.pem", dest+"/other_key.pem", dest+"/cert.p12", ""); !errors.Is(err, ErrKeyMismatch) {
+		t.Errorf("ExportPKCS12() error = %v, want %v", err, ErrKeyMismatch)
+	}
+
+	if _, err := os.Stat(dest + "/cert.p12"); !os.IsNotExist(err) {
+		t.Errorf("ExportPKCS12() should not write a bundle for mismatched key")
+	}
+}
+
+func TestParsePKCS12FileOpenSSL(t *testing.T) {
+	// created by OpenSSL 3 with default settings (PBES2 encrypted certificates):
+	// openssl pkcs12 -export -in cert.pem -inkey key.pem -certfile ca_cert.pem -passout pass:secret
+	cert, priv, chain, err := ParsePKCS12File("testdata/openssl_default.p12", "secret")
+	if err != nil {
+		t.Fatalf("ParsePKCS12File() error = %v", err)
+	}
+
+	if cert.Subject.CommonName != "openssl.example.com" {
+		t.Errorf("CommonName = %q, want %q", cert.Subject.CommonName, "openssl.example.com")
+	}
+
+	if !matchesPublicKey(priv, cert.PublicKey) {
+		t.Errorf("ParsePKCS12File() key does not match the certificate")
+	}
+
+	if len(chain) != 1 || chain[0].Subject.CommonName != "OpenSSL Test CA" {
+		t.Errorf("chain = %v, want the OpenSSL Test CA", chain)
+	}
+
+	if _, _, _, err = ParsePKCS12File("testdata/openssl_default.p12", "wrong"); err == nil {
+		t.Errorf("ParsePKCS12File() expected error for wrong password")
+	}
+}
+
+func TestPKCS12KDFKnownAnswers(t *testing.T) {
+	// the first vector is the published RFC 7292 test vector, all were checked with
+	// openssl kdf PKCS12KDF
+	tests := []struct {
+		name       string
+		h          func() hash.Hash
+		password   string
+		salt       string
+		iterations int
+		id         byte
+		want       string
+	}{
+		{
+			name:       "SHA-1 key material",
+			h:          sha1.New,
+			password:   "smeg",
+			salt:       "0a58cf64530d823f",
+			iterations: 1,
+			id:         1,
+			want:       "8aaae6297b6cb04642ab5b077851284eb7128f1a2a7fbca3",
+		},
+		{
+			name:       "SHA-1 MAC key",
+			h:          sha1.New,
+			password:   "queeg",
+			salt:       "3d83c0e4546ac140",
+			iterations: 1000,
+			id:         3,
+			want:       "17b9e78ea534fc2b6a35512d03799d9ea3c461c0",
+		},
+		{
+			name:       "SHA-256 MAC key",
+			h:          sha256.New,
+			password:   "secret",
+			salt:       "0102030405060708",
+			iterations: 2048,
+			id:         3,
+			want:       "f5482fd03f702689b4e96cbbea867c6b16e5bda934929f2ecaf4da9e1c67be8f",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			salt, _ := hex.DecodeString(tt.salt)
+			want, _ := hex.DecodeString(tt.want)
+			got := pkcs12KDF(tt.h, salt, bmpString(tt.password), tt.iterations, tt.id, len(want))
+			if !bytes.Equal(got, want) {
+				t.Errorf("pkcs12KDF() = %x, want %x", got, want)
+			}
+		})
+	}
+}
+
+func TestParsePKCS12IterationCount(t *testing.T) {
+	data, err := os.ReadFile("testdata/openssl_default.p12")
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+
+	for _, iterations := range []int{0, -1, 1<<31 - 1} {
+		t.Run(fmt.Sprint(iterations), func(t *testing.T) {
+			var pfx pfxPdu
+			if _, err := asn1.Unmarshal(data, &pfx); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+
+			pfx.MacData.Iterations = iterations
+			crafted, err := asn1.Marshal(pfx)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+
+			_, _, _, err = decodePKCS12(crafted, "secret")
+			if err == nil || !strings.Contains(err.Error(), "iteration count") {
+				t.Errorf("decodePKCS12() error = %v, want iteration count error", err)
+			}
+		})
+	}
+}
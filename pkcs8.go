@@ -0,0 +1,278 @@
+package gcert
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+)
+
+// PKCS#8 private key encryption using PBES2 (RFC 8018) with PBKDF2-HMAC-SHA256
+// as key derivation function and AES-256-CBC as encryption scheme.
+// Decryption also accepts the HMAC-SHA1/384/512 PRFs and AES-128/192-CBC, which
+// covers keys encrypted by openssl.
+
+const (
+	pbkdf2Iterations = 600000
+	pbkdf2SaltSize   = 16
+	// maxKDFIterations caps the iteration counts read from encrypted keys and PKCS#12
+	// bundles, a crafted file could otherwise keep the key derivation busy for hours
+	maxKDFIterations = 10000000
+)
+
+var (
+	oidPBES2          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACWithSHA384 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 10}
+	oidHMACWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+	oidAES128CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+// pbkdf2PRF returns the hash of the PBKDF2 pseudorandom function, which defaults to HMAC-SHA1
+func pbkdf2PRF(prf asn1.ObjectIdentifier) (func() hash.Hash, error) {
+	switch {
+	case len(prf) == 0, prf.Equal(oidHMACWithSHA1):
+		return sha1.New, nil
+	case prf.Equal(oidHMACWithSHA256):
+		return sha256.New, nil
+	case prf.Equal(oidHMACWithSHA384):
+		return sha512.New384, nil
+	case prf.Equal(oidHMACWithSHA512):
+		return sha512.New, nil
+	default:
+		return nil, fmt.Errorf("unsupported PBKDF2 pseudorandom function: %v", prf)
+	}
+}
+
+// aesKeySize returns the key size of the AES-CBC encryption scheme
+func aesKeySize(scheme asn1.ObjectIdentifier) (int, error) {
+	switch {
+	case scheme.Equal(oidAES128CBC):
+		return 16, nil
+	case scheme.Equal(oidAES192CBC):
+		return 24, nil
+	case scheme.Equal(oidAES256CBC):
+		return 32, nil
+	default:
+		return 0, fmt.Errorf("unsupported encryption scheme: %v", scheme)
+	}
+}
+
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// encryptPKCS8 encrypts the DER encoded PKCS#8 private key with the given password
+// and returns the DER encoded EncryptedPrivateKeyInfo
+func encryptPKCS8(der, password []byte) ([]byte, error) {
+	salt := make([]byte, pbkdf2SaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(iv); err != nil {
+		return nil, fmt.Errorf("failed to generate IV: %w", err)
+	}
+
+	key := pbkdf2Key(sha256.New, password, salt, pbkdf2Iterations, 32)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	padding := aes.BlockSize - len(der)%aes.BlockSize
+	encrypted := append(append([]byte{}, der...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, encrypted)
+
+	kdfParams, err := asn1.Marshal(pbkdf2Params{
+		Salt:           salt,
+		IterationCount: pbkdf2Iterations,
+		PRF: pkix.AlgorithmIdentifier{
+			Algorithm:  oidHMACWithSHA256,
+			Parameters: asn1.NullRawValue,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	encIV, err := asn1.Marshal(iv)
+	if err != nil {
+		return nil, err
+	}
+
+	params, err := asn1.Marshal(pbes2Params{
+		KeyDerivationFunc: pkix.AlgorithmIdentifier{
+			Algorithm:  oidPBKDF2,
+			Parameters: asn1.RawValue{FullBytes: kdfParams},
+		},
+		EncryptionScheme: pkix.AlgorithmIdentifier{
+			Algorithm:  oidAES256CBC,
+			Parameters: asn1.RawValue{FullBytes: encIV},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(encryptedPrivateKeyInfo{
+		Algorithm: pkix.AlgorithmIdentifier{
+			Algorithm:  oidPBES2,
+			Parameters: asn1.RawValue{FullBytes: params},
+		},
+		EncryptedData: encrypted,
+	})
+}
+
+// decryptPKCS8 decrypts the DER encoded EncryptedPrivateKeyInfo with the given password
+// and returns the DER encoded PKCS#8 private key
+func decryptPKCS8(der, password []byte) ([]byte, error) {
+	var info encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse encrypted private key: %w", err)
+	}
+
+	decrypted, err := decryptPBES2(info.Algorithm, info.EncryptedData, password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt private key: %w", err)
+	}
+
+	return decrypted, nil
+}
+
+// decryptPBES2 decrypts the data encrypted with the given PBES2 algorithm, as used by
+// encrypted PKCS#8 keys and the encrypted content of PKCS#12 bundles
+func decryptPBES2(algorithm pkix.AlgorithmIdentifier, encrypted, password []byte) ([]byte, error) {
+	if !algorithm.Algorithm.Equal(oidPBES2) {
+		return nil, fmt.Errorf("unsupported encryption algorithm: %v", algorithm.Algorithm)
+	}
+
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, fmt.Errorf("failed to parse PBES2 parameters: %w", err)
+	}
+
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, fmt.Errorf("unsupported key derivation function: %v", params.KeyDerivationFunc.Algorithm)
+	}
+
+	var kdfParams pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdfParams); err != nil {
+		return nil, fmt.Errorf("failed to parse PBKDF2 parameters: %w", err)
+	}
+
+	if err := checkIterations(kdfParams.IterationCount); err != nil {
+		return nil, err
+	}
+
+	prf, err := pbkdf2PRF(kdfParams.PRF.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	keySize, err := aesKeySize(params.EncryptionScheme.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, fmt.Errorf("failed to parse encryption IV: %w", err)
+	}
+
+	if len(iv) != aes.BlockSize {
+		return nil, errors.New("invalid encryption IV length")
+	}
+
+	if len(encrypted) == 0 || len(encrypted)%aes.BlockSize != 0 {
+		return nil, errors.New("invalid encrypted data length")
+	}
+
+	if kdfParams.KeyLength != 0 && kdfParams.KeyLength != keySize {
+		return nil, errors.New("invalid PBKDF2 key length")
+	}
+
+	key := pbkdf2Key(prf, password, kdfParams.Salt, kdfParams.IterationCount, keySize)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	decrypted := make([]byte, len(encrypted))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(decrypted, encrypted)
+
+	padding := int(decrypted[len(decrypted)-1])
+	if padding == 0 || padding > aes.BlockSize || !bytes.Equal(decrypted[len(decrypted)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, errors.New("incorrect password")
+	}
+
+	return decrypted[:len(decrypted)-padding], nil
+}
+
+// checkIterations rejects iteration counts that are not positive or above maxKDFIterations
+func checkIterations(n int) error {
+	if n <= 0 || n > maxKDFIterations {
+		return fmt.Errorf("invalid key derivation iteration count %d, must be between 1 and %d", n, maxKDFIterations)
+	}
+
+	return nil
+}
+
+// pbkdf2Key derives a key from the password and salt as described in RFC 8018 section 5.2
+func pbkdf2Key(h func() hash.Hash, password, salt []byte, iter, keyLen int) []byte {
+	prf := hmac.New(h, password)
+	hashLen := prf.Size()
+	numBlocks := (keyLen + hashLen - 1) / hashLen
+
+	var buf [4]byte
+	dk := make([]byte, 0, numBlocks*hashLen)
+	u := make([]byte, hashLen)
+	for block := 1; block <= numBlocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(buf[:], uint32(block))
+		prf.Write(buf[:])
+		dk = prf.Sum(dk)
+		t := dk[len(dk)-hashLen:]
+		copy(u, t)
+
+		for n := 2; n <= iter; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for i := range u {
+				t[i] ^= u[i]
+			}
+		}
+	}
+
+	return dk[:keyLen]
+}
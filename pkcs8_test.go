@@ -0,0 +1,174 @@
+package gcert
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/ecdsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+	"testing"
+)
+
+func TestGenerateWithKeyPassphrase(t *testing.T) {
+	dest := t.TempDir()
+	passphrase := []byte("correct horse battery staple")
+
+	if err := Generate("test.example.com", dest, WithP256(), WithKeyPassphrase(passphrase)); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	cert, err := ParsePemCertFile(dest + "/cert.pem")
+	if err != nil {
+		t.Fatalf("ParsePemCertFile() error = %v", err)
+	}
+
+	if _, err = ParsePemKeyFile(dest + "/key.pem"); !errors.Is(err, ErrEncryptedKey) {
+		t.Errorf("ParsePemKeyFile() error = %v, want %v", err, ErrEncryptedKey)
+	}
+
+	priv, err := ParseEncryptedPemKeyFile(dest+"/key.pem", passphrase)
+	if err != nil {
+		t.Fatalf("ParseEncryptedPemKeyFile() error = %v", err)
+	}
+
+	if !priv.(*ecdsa.PrivateKey).PublicKey.Equal(cert.PublicKey) {
+		t.Errorf("ParseEncryptedPemKeyFile() key does not match certificate")
+	}
+
+	if _, err = ParseEncryptedPemKeyFile(dest+"/key.pem", []byte("wrong passphrase")); err == nil {
+		t.Errorf("ParseEncryptedPemKeyFile() expected error for wrong passphrase")
+	}
+}
+
+func TestParseEncryptedPemKeyFileOpenSSL(t *testing.T) {
+	want, err := ParsePemKeyFile("testdata/key.pem")
+	if err != nil {
+		t.Fatalf("ParsePemKeyFile() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{
+			name: "AES-256-CBC with HMAC-SHA256",
+			path: "testdata/encrypted_key.pem",
+		},
+		{
+			name: "AES-128-CBC with default HMAC-SHA1",
+			path: "testdata/encrypted_key_sha1.pem",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParsePemKeyFile(tt.path); !errors.Is(err, ErrEncryptedKey) {
+				t.Errorf("ParsePemKeyFile() error = %v, want %v", err, ErrEncryptedKey)
+			}
+
+			priv, err := ParseEncryptedPemKeyFile(tt.path, []byte("secret"))
+			if err != nil {
+				t.Fatalf("ParseEncryptedPemKeyFile() error = %v", err)
+			}
+
+			if !priv.(*ecdsa.PrivateKey).Equal(want) {
+				t.Errorf("ParseEncryptedPemKeyFile() key does not match the unencrypted key")
+			}
+
+			if _, err = ParseEncryptedPemKeyFile(tt.path, []byte("wrong")); err == nil {
+				t.Errorf("ParseEncryptedPemKeyFile() expected error for wrong password")
+			}
+		})
+	}
+}
+
+func TestPBKDF2KnownAnswers(t *testing.T) {
+	// RFC 6070 (HMAC-SHA1) and RFC 7914 section 11 (HMAC-SHA256) test vectors
+	tests := []struct {
+		name       string
+		h          func() hash.Hash
+		password   string
+		salt       string
+		iterations int
+		want       string
+	}{
+		{
+			name:       "HMAC-SHA1 1 iteration",
+			h:          sha1.New,
+			password:   "password",
+			salt:       "salt",
+			iterations: 1,
+			want:       "0c60c80f961f0e71f3a9b524af6012062fe037a6",
+		},
+		{
+			name:       "HMAC-SHA1 2 iterations",
+			h:          sha1.New,
+			password:   "password",
+			salt:       "salt",
+			iterations: 2,
+			want:       "ea6c014dc72d6f8ccd1ed92ace1d41f0d8de8957",
+		},
+		{
+			name:       "HMAC-SHA1 4096 iterations",
+			h:          sha1.New,
+			password:   "password",
+			salt:       "salt",
+			iterations: 4096,
+			want:       "4b007901b765489abead49d926f721d065a429c1",
+		},
+		{
+			name:       "HMAC-SHA256 multiple blocks",
+			h:          sha256.New,
+			password:   "passwd",
+			salt:       "salt",
+			iterations: 1,
+			want: "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc" +
+				"49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, _ := hex.DecodeString(tt.want)
+			got := pbkdf2Key(tt.h, []byte(tt.password), []byte(tt.salt), tt.iterations, len(want))
+			if !bytes.Equal(got, want) {
+				t.Errorf("pbkdf2Key() = %x, want %x", got, want)
+			}
+		})
+	}
+}
+
+func TestDecryptPBES2IterationCount(t *testing.T) {
+	for _, iterations := range []int{0, -1, maxKDFIterations + 1} {
+		t.Run(fmt.Sprint(iterations), func(t *testing.T) {
+			kdfParams, err := asn1.Marshal(pbkdf2Params{Salt: make([]byte, pbkdf2SaltSize), IterationCount: iterations})
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+
+			iv, err := asn1.Marshal(make([]byte, aes.BlockSize))
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+
+			params, err := asn1.Marshal(pbes2Params{
+				KeyDerivationFunc: pkix.AlgorithmIdentifier{Algorithm: oidPBKDF2, Parameters: asn1.RawValue{FullBytes: kdfParams}},
+				EncryptionScheme:  pkix.AlgorithmIdentifier{Algorithm: oidAES256CBC, Parameters: asn1.RawValue{FullBytes: iv}},
+			})
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+
+			alg := pkix.AlgorithmIdentifier{Algorithm: oidPBES2, Parameters: asn1.RawValue{FullBytes: params}}
+			_, err = decryptPBES2(alg, make([]byte, aes.BlockSize), []byte("secret"))
+			if err == nil || !strings.Contains(err.Error(), "iteration count") {
+				t.Errorf("decryptPBES2() error = %v, want iteration count error", err)
+			}
+		})
+	}
+}
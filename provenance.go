@@ -0,0 +1,57 @@
+package gcert
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Provenance is the audit record written by WithProvenanceFile
+type Provenance struct {
+	Hosts        []string  `json:"hosts"`
+	KeyType      string    `json:"key_type"`
+	NotBefore    time.Time `json:"not_before"`
+	NotAfter     time.Time `json:"not_after"`
+	SerialNumber string    `json:"serial_number"`
+	Fingerprint  string    `json:"fingerprint"`
+}
+
+// writeProvenance writes the JSON provenance record of the certificate to path
+func writeProvenance(path string, cert *x509.Certificate, o *options) error {
+	p := Provenance{
+		Hosts:        append([]string{}, cert.DNSNames...),
+		KeyType:      keyType(cert.PublicKey),
+		NotBefore:    cert.NotBefore,
+		NotAfter:     cert.NotAfter,
+		SerialNumber: cert.SerialNumber.String(),
+		Fingerprint:  Fingerprint(cert),
+	}
+	for _, ip := range cert.IPAddresses {
+		p.Hosts = append(p.Hosts, ip.String())
+	}
+
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal provenance: %w", err)
+	}
+
+	return writeFile(path, append(data, '\n'), o.certMode, o.noClobber)
+}
+
+// keyType describes the public key, e.g. RSA-2048, ECDSA-P-256 or Ed25519
+func keyType(pub any) string {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA-%d", k.N.BitLen())
+	case *ecdsa.PublicKey:
+		return "ECDSA-" + k.Curve.Params().Name
+	case ed25519.PublicKey:
+		return "Ed25519"
+	default:
+		return fmt.Sprintf("%T", pub)
+	}
+}
@@ -0,0 +1,107 @@
+package gcert
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestGenerateWithProvenanceFile(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        []Option
+		wantKeyType string
+	}{
+		{
+			name:        "ECDSA",
+			opts:        []Option{WithP256()},
+			wantKeyType: "ECDSA-P-256",
+		},
+		{
+			name:        "RSA",
+			opts:        []Option{WithRSABits(2048)},
+			wantKeyType: "RSA-2048",
+		},
+		{
+			name:        "ED25519",
+			opts:        []Option{WithED25519()},
+			wantKeyType: "Ed25519",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			path := dest + "/provenance.json"
+			opts := append([]Option{WithProvenanceFile(path)}, tt.opts...)
+			if err := Generate("test.example.com,127.0.0.1", dest, opts...); err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+
+			cert, err := ParsePemCertFile(dest + "/cert.pem")
+			if err != nil {
+				t.Fatalf("ParsePemCertFile() error = %v", err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("ReadFile() error = %v", err)
+			}
+
+			var got Provenance
+			if err = json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+
+			if got.SerialNumber != cert.SerialNumber.String() {
+				t.Errorf("SerialNumber = %s, want %s", got.SerialNumber, cert.SerialNumber)
+			}
+
+			if got.Fingerprint != Fingerprint(cert) {
+				t.Errorf("Fingerprint = %s, want %s", got.Fingerprint, Fingerprint(cert))
+			}
+
+			if got.KeyType != tt.wantKeyType {
+				t.Errorf("KeyType = %s, want %s", got.KeyType, tt.wantKeyType)
+			}
+
+			if want := []string{"test.example.com", "127.0.0.1"}; !reflect.DeepEqual(got.Hosts, want) {
+				t.Errorf("Hosts = %v, want %v", got.Hosts, want)
+			}
+
+			if !got.NotBefore.Equal(cert.NotBefore) || !got.NotAfter.Equal(cert.NotAfter) {
+				t.Errorf("validity = %v - %v, want %v - %v", got.NotBefore, got.NotAfter, cert.NotBefore, cert.NotAfter)
+			}
+		})
+	}
+}
+
+func TestGenerateWithProvenanceFileOnFailure(t *testing.T) {
+	dest := t.TempDir()
+	path := dest + "/provenance.json"
+	if err := Generate("", dest, WithP256(), WithProvenanceFile(path)); err == nil {
+		t.Fatalf("Generate() expected error for missing host")
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("provenance file should not be written when generation fails")
+	}
+}
+
+func TestGenerateWithProvenanceFileNoClobber(t *testing.T) {
+	dest := t.TempDir()
+	path := dest + "/provenance.json"
+	if err := os.WriteFile(path, []byte("{}\n"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	if err := Generate("test.example.com", dest, WithP256(), WithNoClobber(), WithProvenanceFile(path)); err == nil {
+		t.Fatalf("Generate() expected error for existing provenance file")
+	}
+
+	for _, name := range []string{"cert.pem", "key.pem"} {
+		if _, err := os.Stat(dest + "/" + name); !os.IsNotExist(err) {
+			t.Errorf("%s should not be written when the provenance file exists", name)
+		}
+	}
+}
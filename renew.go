@@ -0,0 +1,168 @@
+package gcert
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Renew reissues the certificate at certPath into dest directory keeping its subject,
+// subject alternative names and extended key usages, with a new serial number, a fresh
+// validity window (see WithDuration) and a new private key of the same type as the old one
+// unless a key type option (e.g. WithP256) is given. keyPath is the private key
+// of the renewed certificate, which is only used with WithReuseKey. The renewed
+// certificate is self-signed unless WithSignByParent is given.
+func Renew(certPath, keyPath, dest string, opts ...Option) error {
+	return reissue(certPath, keyPath, dest, false, opts)
+}
+
+// Rekey reissues the certificate at certPath into dest directory like Renew but keeps
+// its validity dates, key usage, basic and name constraints and certificate policies,
+// only the private key (of the old or the given key type) and serial number change. The
+// certificate is self-signed unless WithSignByParent is given.
+func Rekey(certPath, dest string, opts ...Option) error {
+	return reissue(certPath, "", dest, true, opts)
+}
+
+// reissue generates a certificate from the template of the certificate at certPath,
+// keeping its validity dates when keepValidity is set
+func reissue(certPath, keyPath, dest string, keepValidity bool, opts []Option) error {
+	old, err := ParsePemCertFile(certPath)
+	if err != nil {
+		return err
+	}
+
+	o := initOptions()
+	o.subject = old.Subject
+	if o.subject.ExtraNames, err = subjectExtraNames(old); err != nil {
+		return err
+	}
+	o.emails = old.EmailAddresses
+	o.extKeyUsage = old.ExtKeyUsage
+	o.isCA = old.IsCA
+	for _, uri := range old.URIs {
+		o.uris = append(o.uris, uri.String())
+	}
+
+	if keepValidity {
+		o.notBefore, o.notAfter = old.NotBefore, old.NotAfter
+
+		// a rekeyed CA must not be less restricted than the original
+		o.keyUsage = old.KeyUsage
+		o.noBasicConstraints = !old.BasicConstraintsValid
+		if old.MaxPathLen > 0 || old.MaxPathLenZero {
+			o.pathLen = old.MaxPathLen
+		}
+		o.permittedDNS = old.PermittedDNSDomains
+		o.excludedDNS = old.ExcludedDNSDomains
+		o.policyOIDs = old.PolicyIdentifiers
+	}
+
+	if !selectsKeyType(opts) {
+		setKeyType(&o, old.PublicKey)
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	// Rekey has no key of the old certificate, WithExistingKey is kept there
+	if o.reuseKey && len(keyPath) > 0 {
+		o.existingKey = keyPath
+	}
+
+	if err = checkOutputOptions(&o); err != nil {
+		return err
+	}
+
+	hosts := append([]string{}, old.DNSNames...)
+	for _, ip := range old.IPAddresses {
+		hosts = append(hosts, ip.String())
+	}
+
+	cert, priv, err := generate(context.Background(), hosts, &o)
+	if err != nil {
+		return err
+	}
+
+	_, err = writeFiles(context.Background(), dest, cert, priv, &o)
+
+	return err
+}
+
+// selectsKeyType reports whether the options choose the type or size of a generated key
+func selectsKeyType(opts []Option) bool {
+	o := initOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return len(o.ecdsaCurve) > 0 || o.ed25519Key || o.insecureRSA || o.rsaBits != initOptions().rsaBits
+}
+
+// setKeyType selects the key type and size of the public key for generated keys
+func setKeyType(o *options, pub any) {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		o.rsaBits = k.N.BitLen()
+	case *ecdsa.PublicKey:
+		o.ecdsaCurve = strings.ReplaceAll(k.Curve.Params().Name, "-", "")
+	case ed25519.PublicKey:
+		o.ed25519Key = true
+	}
+}
+
+// nameFieldOIDs are the subject attributes pkix.Name has a field for
+var nameFieldOIDs = map[string]bool{
+	"2.5.4.3":  true, // commonName
+	"2.5.4.5":  true, // serialNumber
+	"2.5.4.6":  true, // countryName
+	"2.5.4.7":  true, // localityName
+	"2.5.4.8":  true, // stateOrProvinceName
+	"2.5.4.9":  true, // streetAddress
+	"2.5.4.10": true, // organizationName
+	"2.5.4.11": true, // organizationalUnitName
+	"2.5.4.17": true, // postalCode
+}
+
+// rawAttribute is an AttributeTypeAndValue keeping the encoded value
+type rawAttribute struct {
+	Type  asn1.ObjectIdentifier
+	Value asn1.RawValue
+}
+
+// rawAttributesSET is a RelativeDistinguishedName of rawAttributes
+type rawAttributesSET []rawAttribute
+
+// subjectExtraNames returns the subject attributes of cert without a pkix.Name field
+// (e.g. emailAddress) with their original encoding. A parsed subject only keeps them in
+// Names, which x509.CreateCertificate ignores
+func subjectExtraNames(cert *x509.Certificate) ([]pkix.AttributeTypeAndValue, error) {
+	var rdns []rawAttributesSET
+	rest, err := asn1.Unmarshal(cert.RawSubject, &rdns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate subject: %w", err)
+	}
+
+	if len(rest) > 0 {
+		return nil, errors.New("failed to parse certificate subject: trailing data")
+	}
+
+	var extraNames []pkix.AttributeTypeAndValue
+	for _, rdn := range rdns {
+		for _, atv := range rdn {
+			if !nameFieldOIDs[atv.Type.String()] {
+				extraNames = append(extraNames, pkix.AttributeTypeAndValue{Type: atv.Type, Value: atv.Value})
+			}
+		}
+	}
+
+	return extraNames, nil
+}
@@ -0,0 +1,379 @@
+package gcert
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenew(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        []Option
+		wantSameKey bool
+	}{
+		{
+			name: "with new key",
+			opts: []Option{WithP256()},
+		},
+		{
+			name:        "with ReuseKey",
+			opts:        []Option{WithReuseKey()},
+			wantSameKey: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := t.TempDir()
+			dest := t.TempDir()
+
+			err := Generate("test.example.com,10.0.0.1", src,
+				WithP256(),
+				WithDuration(time.Hour),
+				WithOrganization("Example Inc"),
+				WithSubjectEmail("admin@example.com"),
+				WithEmailSAN("admin@example.com"),
+				WithURISAN("spiffe://trust-domain/workload"),
+				WithClientAuth(),
+			)
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+
+			old, err := ParsePemCertFile(src + "/cert.pem")
+			if err != nil {
+				t.Fatalf("ParsePemCertFile() error = %v", err)
+			}
+
+			if err = Renew(src+"/cert.pem", src+"/key.pem", dest, tt.opts...); err != nil {
+				t.Fatalf("Renew() error = %v", err)
+			}
+
+			renewed, err := ParsePemCertFile(dest + "/cert.pem")
+			if err != nil {
+				t.Fatalf("ParsePemCertFile() error = %v", err)
+			}
+
+			if !bytes.Equal(renewed.RawSubject, old.RawSubject) {
+				t.Errorf("Subject = %v, want %v", renewed.Subject, old.Subject)
+			}
+
+			if !SameIdentity(old, renewed) {
+				t.Errorf("SameIdentity() = false, want true")
+			}
+
+			if !reflect.DeepEqual(renewed.DNSNames, old.DNSNames) {
+				t.Errorf("DNSNames = %v, want %v", renewed.DNSNames, old.DNSNames)
+			}
+
+			if !reflect.DeepEqual(renewed.IPAddresses, old.IPAddresses) {
+				t.Errorf("IPAddresses = %v, want %v", renewed.IPAddresses, old.IPAddresses)
+			}
+
+			if !reflect.DeepEqual(renewed.EmailAddresses, old.EmailAddresses) {
+				t.Errorf("EmailAddresses = %v, want %v", renewed.EmailAddresses, old.EmailAddresses)
+			}
+
+			if !reflect.DeepEqual(renewed.URIs, old.URIs) {
+				t.Errorf("URIs = %v, want %v", renewed.URIs, old.URIs)
+			}
+
+			if !reflect.DeepEqual(renewed.ExtKeyUsage, old.ExtKeyUsage) {
+				t.Errorf("ExtKeyUsage = %v, want %v", renewed.ExtKeyUsage, old.ExtKeyUsage)
+			}
+
+			if !renewed.NotAfter.After(old.NotAfter) {
+				t.Errorf("NotAfter = %v, want after %v", renewed.NotAfter, old.NotAfter)
+			}
+
+			if renewed.SerialNumber.Cmp(old.SerialNumber) == 0 {
+				t.Errorf("SerialNumber was not changed")
+			}
+
+			sameKey := renewed.PublicKey.(interface{ Equal(crypto.PublicKey) bool }).Equal(old.PublicKey)
+			if sameKey != tt.wantSameKey {
+				t.Errorf("same key = %v, want %v", sameKey, tt.wantSameKey)
+			}
+
+			if err = Verify(dest+"/cert.pem", dest+"/cert.pem", "test.example.com"); err != nil {
+				t.Errorf("Verify() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestRekey(t *testing.T) {
+	caDir := t.TempDir()
+	if err := Generate("cadomain.cert", caDir, WithCA(), WithP256()); err != nil {
+		t.Fatalf("Generate() CA error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		opts       []Option
+		wantAlgo   x509.PublicKeyAlgorithm
+		signedByCA bool
+	}{
+		{
+			name:     "self-signed",
+			opts:     []Option{WithED25519()},
+			wantAlgo: x509.Ed25519,
+		},
+		{
+			name:       "signed by parent",
+			opts:       []Option{WithP256(), WithSignByParent(caDir+"/cert.pem", caDir+"/key.pem")},
+			wantAlgo:   x509.ECDSA,
+			signedByCA: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := t.TempDir()
+			dest := t.TempDir()
+
+			err := Generate("test.example.com,10.0.0.1", src,
+				WithP256(),
+				WithValidFrom(time.Now().Add(-time.Hour).Truncate(time.Second)),
+				WithDuration(48*time.Hour),
+				WithOrganization("Example Inc"),
+				WithEmailSAN("admin@example.com"),
+			)
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+
+			old, err := ParsePemCertFile(src + "/cert.pem")
+			if err != nil {
+				t.Fatalf("ParsePemCertFile() error = %v", err)
+			}
+
+			if err = Rekey(src+"/cert.pem", dest, tt.opts...); err != nil {
+				t.Fatalf("Rekey() error = %v", err)
+			}
+
+			rekeyed, err := ParsePemCertFile(dest + "/cert.pem")
+			if err != nil {
+				t.Fatalf("ParsePemCertFile() error = %v", err)
+			}
+
+			if rekeyed.Subject.String() != old.Subject.String() {
+				t.Errorf("Subject = %v, want %v", rekeyed.Subject, old.Subject)
+			}
+
+			if !reflect.DeepEqual(rekeyed.DNSNames, old.DNSNames) || !reflect.DeepEqual(rekeyed.EmailAddresses, old.EmailAddresses) {
+				t.Errorf("SANs = %v %v, want %v %v", rekeyed.DNSNames, rekeyed.EmailAddresses, old.DNSNames, old.EmailAddresses)
+			}
+
+			if len(rekeyed.IPAddresses) != 1 || !rekeyed.IPAddresses[0].Equal(old.IPAddresses[0]) {
+				t.Errorf("IPAddresses = %v, want %v", rekeyed.IPAddresses, old.IPAddresses)
+			}
+
+			if !rekeyed.NotBefore.Equal(old.NotBefore) || !rekeyed.NotAfter.Equal(old.NotAfter) {
+				t.Errorf("validity = %v - %v, want %v - %v", rekeyed.NotBefore, rekeyed.NotAfter, old.NotBefore, old.NotAfter)
+			}
+
+			if rekeyed.PublicKeyAlgorithm != tt.wantAlgo {
+				t.Errorf("PublicKeyAlgorithm = %v, want %v", rekeyed.PublicKeyAlgorithm, tt.wantAlgo)
+			}
+
+			if reflect.DeepEqual(rekeyed.PublicKey, old.PublicKey) {
+				t.Errorf("Rekey() kept the old public key")
+			}
+
+			if rekeyed.SerialNumber.Cmp(old.SerialNumber) == 0 {
+				t.Errorf("Rekey() kept the old serial number")
+			}
+
+			key, err := ParsePemKeyFile(dest + "/key.pem")
+			if err != nil {
+				t.Fatalf("ParsePemKeyFile() error = %v", err)
+			}
+
+			if !matchesPublicKey(key, rekeyed.PublicKey) {
+				t.Errorf("Rekey() key does not match the certificate")
+			}
+
+			if tt.signedByCA {
+				if err = Verify(caDir+"/cert.pem", dest+"/cert.pem", "test.example.com"); err != nil {
+					t.Errorf("Verify() error = %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestRekeyConstrainedCA(t *testing.T) {
+	src := t.TempDir()
+	dest := t.TempDir()
+
+	policy := asn1.ObjectIdentifier{1, 2, 3, 4}
+	err := Generate("", src,
+		WithP256(),
+		WithCA(),
+		WithCommonName("Constrained CA"),
+		WithPathLen(0),
+		WithPermittedDNSDomains("example.com"),
+		WithExcludedDNSDomains("internal.example.com"),
+		WithKeyUsage(x509.KeyUsageCertSign),
+		WithPolicyOID(policy),
+	)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	old, err := ParsePemCertFile(src + "/cert.pem")
+	if err != nil {
+		t.Fatalf("ParsePemCertFile() error = %v", err)
+	}
+
+	if err = Rekey(src+"/cert.pem", dest, WithP256()); err != nil {
+		t.Fatalf("Rekey() error = %v", err)
+	}
+
+	rekeyed, err := ParsePemCertFile(dest + "/cert.pem")
+	if err != nil {
+		t.Fatalf("ParsePemCertFile() error = %v", err)
+	}
+
+	if !rekeyed.IsCA || !rekeyed.BasicConstraintsValid {
+		t.Errorf("IsCA = %v, BasicConstraintsValid = %v, want true", rekeyed.IsCA, rekeyed.BasicConstraintsValid)
+	}
+
+	if rekeyed.MaxPathLen != 0 || !rekeyed.MaxPathLenZero {
+		t.Errorf("MaxPathLen = %d, MaxPathLenZero = %v, want 0, true", rekeyed.MaxPathLen, rekeyed.MaxPathLenZero)
+	}
+
+	if !reflect.DeepEqual(rekeyed.PermittedDNSDomains, old.PermittedDNSDomains) || !reflect.DeepEqual(rekeyed.ExcludedDNSDomains, old.ExcludedDNSDomains) {
+		t.Errorf("name constraints = %v %v, want %v %v", rekeyed.PermittedDNSDomains, rekeyed.ExcludedDNSDomains, old.PermittedDNSDomains, old.ExcludedDNSDomains)
+	}
+
+	if rekeyed.KeyUsage != old.KeyUsage {
+		t.Errorf("KeyUsage = %v, want %v", rekeyed.KeyUsage, old.KeyUsage)
+	}
+
+	if !reflect.DeepEqual(rekeyed.PolicyIdentifiers, []asn1.ObjectIdentifier{policy}) {
+		t.Errorf("PolicyIdentifiers = %v, want %v", rekeyed.PolicyIdentifiers, []asn1.ObjectIdentifier{policy})
+	}
+}
+
+func TestRekeyWithExistingKeyAndReuseKey(t *testing.T) {
+	src := t.TempDir()
+	dest := t.TempDir()
+	if err := Generate("test.example.com", src, WithP256()); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if err := Rekey(src+"/cert.pem", dest, WithExistingKey(src+"/key.pem"), WithReuseKey()); err != nil {
+		t.Fatalf("Rekey() error = %v", err)
+	}
+
+	old, err := ParsePemCertFile(src + "/cert.pem")
+	if err != nil {
+		t.Fatalf("ParsePemCertFile() error = %v", err)
+	}
+
+	rekeyed, err := ParsePemCertFile(dest + "/cert.pem")
+	if err != nil {
+		t.Fatalf("ParsePemCertFile() error = %v", err)
+	}
+
+	if !rekeyed.PublicKey.(interface{ Equal(crypto.PublicKey) bool }).Equal(old.PublicKey) {
+		t.Errorf("Rekey() did not keep the WithExistingKey key")
+	}
+}
+
+func TestRenewKeepsKeyType(t *testing.T) {
+	tests := []struct {
+		name     string
+		genOpts  []Option
+		opts     []Option
+		wantType string
+	}{
+		{
+			name:     "P256 CA",
+			genOpts:  []Option{WithP256(), WithCA()},
+			wantType: "ECDSA-P-256",
+		},
+		{
+			name:     "P384",
+			genOpts:  []Option{WithP384()},
+			wantType: "ECDSA-P-384",
+		},
+		{
+			name:     "Ed25519",
+			genOpts:  []Option{WithED25519()},
+			wantType: "Ed25519",
+		},
+		{
+			name:     "RSA 3072",
+			genOpts:  []Option{WithRSABits(3072)},
+			wantType: "RSA-3072",
+		},
+		{
+			name:     "key type option",
+			genOpts:  []Option{WithP256()},
+			opts:     []Option{WithRSABits(3072)},
+			wantType: "RSA-3072",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := t.TempDir()
+			dest := t.TempDir()
+			if err := Generate("test.example.com", src, tt.genOpts...); err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+
+			if err := Renew(src+"/cert.pem", src+"/key.pem", dest, tt.opts...); err != nil {
+				t.Fatalf("Renew() error = %v", err)
+			}
+
+			renewed, err := ParsePemCertFile(dest + "/cert.pem")
+			if err != nil {
+				t.Fatalf("ParsePemCertFile() error = %v", err)
+			}
+
+			if got := keyType(renewed.PublicKey); got != tt.wantType {
+				t.Errorf("key type = %s, want %s", got, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestSubjectExtraNamesInvalid(t *testing.T) {
+	subject, err := asn1.Marshal(pkix.Name{CommonName: "test"}.ToRDNSequence())
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		raw     []byte
+		wantErr string
+	}{
+		{
+			name:    "trailing data",
+			raw:     append(subject, 0),
+			wantErr: "trailing data",
+		},
+		{
+			name:    "malformed",
+			raw:     []byte{0x30, 0x05},
+			wantErr: "failed to parse certificate subject: asn1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := subjectExtraNames(&x509.Certificate{RawSubject: tt.raw})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("subjectExtraNames() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
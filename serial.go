@@ -0,0 +1,71 @@
+package gcert
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// SerialSource provides the serial numbers of generated certificates (see WithSerialSource),
+// e.g. a monotonically increasing counter of a CA. Next returns nil on failure, a source
+// that also has an Err() error method reports the reason through it
+type SerialSource interface {
+	Next() *big.Int
+}
+
+// FileSerialSource is a SerialSource counting up from 1 that persists the last serial
+// number as hex in the 'serial' file of its directory, like the serial file of openssl ca.
+// It is safe for concurrent use within a process
+type FileSerialSource struct {
+	path string
+
+	mu  sync.Mutex
+	err error
+}
+
+// NewFileSerialSource returns a FileSerialSource persisting the last serial number in dest directory
+func NewFileSerialSource(dest string) *FileSerialSource {
+	return &FileSerialSource{path: filepath.Join(dest, "serial")}
+}
+
+// Next increments and persists the serial number and returns it, or nil if the serial
+// file can not be read or written (see Err)
+func (s *FileSerialSource) Next() *big.Int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	last := new(big.Int)
+	data, err := os.ReadFile(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		s.err = fmt.Errorf("failed to read serial file: %w", err)
+		return nil
+	}
+
+	if err == nil {
+		if _, ok := last.SetString(strings.TrimSpace(string(data)), 16); !ok {
+			s.err = fmt.Errorf("invalid serial number in %s", s.path)
+			return nil
+		}
+	}
+
+	next := last.Add(last, big.NewInt(1))
+	if err = writeFile(s.path, []byte(strings.ToUpper(next.Text(16))+"\n"), 0644, false); err != nil {
+		s.err = err
+		return nil
+	}
+
+	s.err = nil
+
+	return next
+}
+
+// Err returns the error of the last call to Next
+func (s *FileSerialSource) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err
+}
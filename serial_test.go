@@ -0,0 +1,110 @@
+package gcert
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateWithSerialSource(t *testing.T) {
+	caDir := t.TempDir()
+	ca, err := NewCA("cadomain.cert", WithP256())
+	if err != nil {
+		t.Fatalf("NewCA() error = %v", err)
+	}
+
+	source := NewFileSerialSource(caDir)
+	var last int64
+	for i := 0; i < 3; i++ {
+		dest := t.TempDir()
+		if err = ca.Sign("test.example.com", dest, WithP256(), WithSerialSource(source)); err != nil {
+			t.Fatalf("Sign() error = %v", err)
+		}
+
+		cert, err := ParsePemCertFile(dest + "/cert.pem")
+		if err != nil {
+			t.Fatalf("ParsePemCertFile() error = %v", err)
+		}
+
+		if got := cert.SerialNumber.Int64(); got <= last {
+			t.Errorf("SerialNumber = %d, want greater than %d", got, last)
+		}
+		last = cert.SerialNumber.Int64()
+	}
+
+	if last != 3 {
+		t.Errorf("last SerialNumber = %d, want 3", last)
+	}
+
+	data, err := os.ReadFile(caDir + "/serial")
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+
+	if got := strings.TrimSpace(string(data)); got != "3" {
+		t.Errorf("serial file = %q, want %q", got, "3")
+	}
+
+	// a new source continues from the persisted serial number
+	if got := NewFileSerialSource(caDir).Next().Int64(); got != 4 {
+		t.Errorf("Next() = %d, want 4", got)
+	}
+}
+
+func TestFileSerialSourceInvalidFile(t *testing.T) {
+	dest := t.TempDir()
+	if err := os.WriteFile(dest+"/serial", []byte("not hex\n"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	source := NewFileSerialSource(dest)
+	err := Generate("test.example.com", dest, WithP256(), WithSerialSource(source))
+	if err == nil || !strings.Contains(err.Error(), "invalid serial number") {
+		t.Errorf("Generate() error = %v, want the serial file error", err)
+	}
+
+	if source.Err() == nil {
+		t.Errorf("Err() = nil, want error for invalid serial file")
+	}
+
+	if _, err := os.Stat(dest + "/cert.pem"); !os.IsNotExist(err) {
+		t.Errorf("cert.pem should not be written when the serial source fails")
+	}
+}
+
+func TestSerialSourceUntouchedOnFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(dest string, source SerialSource) error
+	}{
+		{
+			name: "Validate",
+			run: func(dest string, source SerialSource) error {
+				return Validate("test.example.com", WithP256(), WithSerialSource(source))
+			},
+		},
+		{
+			name: "failing strict check",
+			run: func(dest string, source SerialSource) error {
+				err := Generate("test.example.com", dest, WithP256(), WithStrict(), WithDuration(2*365*24*time.Hour), WithSerialSource(source))
+				if err == nil {
+					t.Errorf("Generate() expected strict mode error")
+				}
+				return nil
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			if err := tt.run(dest, NewFileSerialSource(dest)); err != nil {
+				t.Fatalf("error = %v", err)
+			}
+
+			if _, err := os.Stat(dest + "/serial"); !os.IsNotExist(err) {
+				t.Errorf("serial file should not be written, stat error = %v", err)
+			}
+		})
+	}
+}
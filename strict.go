@@ -0,0 +1,50 @@
+package gcert
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"time"
+)
+
+// strictMaxValidity caps the validity of non-CA certificates with WithStrict,
+// the CA/Browser Forum limit for TLS server certificates
+const strictMaxValidity = 398 * 24 * time.Hour
+
+// checkStrict rejects weak crypto for WithStrict: RSA keys below 2048 bits, the P224
+// curve and SHA-1 (or MD5) signatures, and non-CA certificates valid for longer than
+// strictMaxValidity unless WithMaxValidity sets the cap. pub is the public key of the
+// certificate and signerPub the public key of its signer
+func checkStrict(template *x509.Certificate, pub, signerPub any, o *options) error {
+	for _, key := range []struct {
+		name string
+		key  any
+	}{{"subject key", pub}, {"signer key", signerPub}} {
+		switch k := key.key.(type) {
+		case *rsa.PublicKey:
+			if bits := k.N.BitLen(); bits < minRSABits {
+				return fmt.Errorf("%w: %s RSA size %d is below %d bits", ErrStrict, key.name, bits, minRSABits)
+			}
+		case *ecdsa.PublicKey:
+			if k.Curve == elliptic.P224() {
+				return fmt.Errorf("%w: %s uses the P224 curve", ErrStrict, key.name)
+			}
+		}
+	}
+
+	switch o.signatureAlgorithm {
+	case x509.MD5WithRSA, x509.SHA1WithRSA, x509.ECDSAWithSHA1:
+		return fmt.Errorf("%w: signature algorithm %v is weak", ErrStrict, o.signatureAlgorithm)
+	}
+
+	if !template.IsCA && o.maxValidity == 0 {
+		// the WithClockSkew backdate is not counted, as with WithMaxValidity
+		if validity := template.NotAfter.Sub(template.NotBefore) - o.clockSkew; validity > strictMaxValidity {
+			return fmt.Errorf("%w: validity %v exceeds the maximum of %v", ErrStrict, validity, strictMaxValidity)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package gcert
+
+import (
+	"crypto/x509"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGenerateWithStrict(t *testing.T) {
+	caDir := t.TempDir()
+	if err := Generate("cadomain.cert", caDir, WithCA(), WithP224()); err != nil {
+		t.Fatalf("Generate() CA error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr bool
+	}{
+		{
+			name: "ECDSA P256",
+			opts: []Option{WithP256()},
+		},
+		{
+			name: "RSA 2048",
+			opts: []Option{WithRSABits(2048)},
+		},
+		{
+			name: "CA valid for ten years",
+			opts: []Option{WithP256(), WithCA(), WithDuration(10 * 365 * 24 * time.Hour)},
+		},
+		{
+			name: "validity within WithMaxValidity",
+			opts: []Option{WithP256(), WithDuration(2 * 365 * 24 * time.Hour), WithMaxValidity(3 * 365 * 24 * time.Hour)},
+		},
+		{
+			name:    "small RSA key",
+			opts:    []Option{WithInsecureRSABits(1024)},
+			wantErr: true,
+		},
+		{
+			name:    "P224 curve",
+			opts:    []Option{WithP224()},
+			wantErr: true,
+		},
+		{
+			name:    "P224 signer",
+			opts:    []Option{WithP256(), WithSignByParent(caDir+"/cert.pem", caDir+"/key.pem")},
+			wantErr: true,
+		},
+		{
+			name:    "SHA-1 RSA signature",
+			opts:    []Option{WithRSABits(2048), WithSignatureAlgorithm(x509.SHA1WithRSA)},
+			wantErr: true,
+		},
+		{
+			name:    "SHA-1 ECDSA signature",
+			opts:    []Option{WithP256(), WithSignatureAlgorithm(x509.ECDSAWithSHA1)},
+			wantErr: true,
+		},
+		{
+			name:    "default validity cap",
+			opts:    []Option{WithP256(), WithDuration(2 * 365 * 24 * time.Hour)},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			err := Generate("test.example.com", dest, append([]Option{WithStrict()}, tt.opts...)...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Generate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr && !errors.Is(err, ErrStrict) {
+				t.Errorf("Generate() error = %v, want %v", err, ErrStrict)
+			}
+
+			// the same options are accepted without strict mode
+			if err = Generate("test.example.com", t.TempDir(), tt.opts...); errors.Is(err, ErrStrict) {
+				t.Errorf("Generate() without WithStrict error = %v", err)
+			}
+		})
+	}
+}
+
+func TestSignCSRWithStrict(t *testing.T) {
+	caDir := t.TempDir()
+	if err := Generate("cadomain.cert", caDir, WithCA(), WithP256()); err != nil {
+		t.Fatalf("Generate() CA error = %v", err)
+	}
+
+	weakCADir := t.TempDir()
+	if err := Generate("cadomain.cert", weakCADir, WithCA(), WithP224()); err != nil {
+		t.Fatalf("Generate() CA error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		csrOpts []Option
+		caDir   string
+		opts    []Option
+		wantErr bool
+	}{
+		{
+			name:    "ECDSA P256",
+			csrOpts: []Option{WithP256()},
+			caDir:   caDir,
+		},
+		{
+			name:    "small RSA key",
+			csrOpts: []Option{WithInsecureRSABits(1024)},
+			caDir:   caDir,
+			wantErr: true,
+		},
+		{
+			name:    "P224 curve",
+			csrOpts: []Option{WithP224()},
+			caDir:   caDir,
+			wantErr: true,
+		},
+		{
+			name:    "P224 CA",
+			csrOpts: []Option{WithP256()},
+			caDir:   weakCADir,
+			wantErr: true,
+		},
+		{
+			name:    "SHA-1 signature",
+			csrOpts: []Option{WithP256()},
+			caDir:   caDir,
+			opts:    []Option{WithSignatureAlgorithm(x509.ECDSAWithSHA1)},
+			wantErr: true,
+		},
+		{
+			name:    "ten year leaf",
+			csrOpts: []Option{WithP256()},
+			caDir:   caDir,
+			opts:    []Option{WithDuration(10 * 365 * 24 * time.Hour)},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			if err := GenerateCSR("test.example.com", dest, tt.csrOpts...); err != nil {
+				t.Fatalf("GenerateCSR() error = %v", err)
+			}
+
+			err := SignCSR(dest+"/csr.pem", tt.caDir+"/cert.pem", tt.caDir+"/key.pem", dest, append([]Option{WithStrict()}, tt.opts...)...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SignCSR() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr && !errors.Is(err, ErrStrict) {
+				t.Errorf("SignCSR() error = %v, want %v", err, ErrStrict)
+			}
+		})
+	}
+}
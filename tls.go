@@ -0,0 +1,93 @@
+package gcert
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+)
+
+// GenerateTLS generates a certificate in memory and returns it as a tls.Certificate
+// ready to be used in a tls.Config. When signed by a parent, the parent certificate
+// is appended to the chain.
+// host is a comma-separated hostnames and IPs to generate a certificate for
+func GenerateTLS(host string, opts ...Option) (tls.Certificate, error) {
+	o := initOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	cert, priv, err := generate(context.Background(), splitHosts(host), &o)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	tlsCert := tls.Certificate{
+		Certificate: [][]byte{cert.Raw},
+		PrivateKey:  priv,
+		Leaf:        cert,
+	}
+
+	if len(o.parentCert) > 0 {
+		chain, err := ParsePemCertChainFile(o.parentCert)
+		if err != nil {
+			return tls.Certificate{}, err
+		}
+
+		for _, c := range chain {
+			tlsCert.Certificate = append(tlsCert.Certificate, c.Raw)
+		}
+	}
+
+	return tlsCert, nil
+}
+
+// ServerTLSConfig generates a certificate like GenerateTLS and returns a tls.Config serving it.
+// When signed by a parent (see WithSignByParent), client certificates issued by the parent
+// are verified if presented, set ClientAuth to tls.RequireAndVerifyClientCert to enforce mTLS.
+// host is a comma-separated hostnames and IPs to generate a certificate for
+func ServerTLSConfig(host string, opts ...Option) (*tls.Config, error) {
+	cert, err := GenerateTLS(host, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if len(cert.Certificate) > 1 {
+		clientCAs := x509.NewCertPool()
+		for _, der := range cert.Certificate[1:] {
+			c, err := x509.ParseCertificate(der)
+			if err != nil {
+				return nil, err
+			}
+			clientCAs.AddCert(c)
+		}
+
+		config.ClientCAs = clientCAs
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return config, nil
+}
+
+// ClientTLSConfig returns a tls.Config trusting the certificates of the given pem CA file.
+// For mTLS add a client certificate (e.g. GenerateTLS with WithClientAuth) to Certificates
+func ClientTLSConfig(caPath string) (*tls.Config, error) {
+	cas, err := ParsePemCertChainFile(caPath)
+	if err != nil {
+		return nil, err
+	}
+
+	roots := x509.NewCertPool()
+	for _, c := range cas {
+		roots.AddCert(c)
+	}
+
+	return &tls.Config{
+		RootCAs:    roots,
+		MinVersion: tls.VersionTLS12,
+	}, nil
+}
@@ -0,0 +1,187 @@
+package gcert
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"testing"
+)
+
+func TestGenerateTLS(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []Option
+		withCA    bool
+		wantChain int
+	}{
+		{
+			name:      "self-signed",
+			opts:      []Option{WithP256()},
+			wantChain: 1,
+		},
+		{
+			name:      "signed by parent",
+			opts:      []Option{WithP256()},
+			withCA:    true,
+			wantChain: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			if tt.withCA {
+				dest := t.TempDir()
+				if err := Generate("cadomain.cert", dest, WithCA(), WithP256()); err != nil {
+					t.Fatalf("Generate() CA error = %v", err)
+				}
+				opts = append(opts, WithSignByParent(dest+"/cert.pem", dest+"/key.pem"))
+			}
+
+			cert, err := GenerateTLS("localhost,127.0.0.1", opts...)
+			if err != nil {
+				t.Fatalf("GenerateTLS() error = %v", err)
+			}
+
+			if len(cert.Certificate) != tt.wantChain {
+				t.Fatalf("GenerateTLS() chain length = %d, want %d", len(cert.Certificate), tt.wantChain)
+			}
+
+			ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+			if err != nil {
+				t.Fatalf("tls.Listen() error = %v", err)
+			}
+			defer ln.Close()
+
+			go func() {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				conn.Write([]byte("ok"))
+			}()
+
+			roots := x509.NewCertPool()
+			roots.AddCert(x509Root(t, cert))
+
+			conn, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{RootCAs: roots, ServerName: "localhost"})
+			if err != nil {
+				t.Fatalf("tls.Dial() error = %v", err)
+			}
+			defer conn.Close()
+
+			got, err := io.ReadAll(conn)
+			if err != nil {
+				t.Fatalf("ReadAll() error = %v", err)
+			}
+
+			if string(got) != "ok" {
+				t.Errorf("read %q, want %q", got, "ok")
+			}
+		})
+	}
+}
+
+// x509Root returns the last certificate of the chain, which is the trust anchor
+func x509Root(t *testing.T, cert tls.Certificate) *x509.Certificate {
+	root, err := x509.ParseCertificate(cert.Certificate[len(cert.Certificate)-1])
+	if err != nil {
+		t.Fatalf("ParseCertificate() error = %v", err)
+	}
+
+	return root
+}
+
+func TestServerClientTLSConfigMutualTLS(t *testing.T) {
+	caDir := t.TempDir()
+	if err := Generate("cadomain.cert", caDir, WithCA(), WithP256(), WithClientAuth()); err != nil {
+		t.Fatalf("Generate() CA error = %v", err)
+	}
+	caCert, caKey := caDir+"/cert.pem", caDir+"/key.pem"
+
+	serverConfig, err := ServerTLSConfig("localhost,127.0.0.1", WithP256(), WithSignByParent(caCert, caKey))
+	if err != nil {
+		t.Fatalf("ServerTLSConfig() error = %v", err)
+	}
+	serverConfig.ClientAuth = tls.RequireAndVerifyClientCert
+
+	clientConfig, err := ClientTLSConfig(caCert)
+	if err != nil {
+		t.Fatalf("ClientTLSConfig() error = %v", err)
+	}
+	clientConfig.ServerName = "localhost"
+
+	clientCert, err := GenerateTLS("client.example.com", WithP256(), WithExtKeyUsage(x509.ExtKeyUsageClientAuth), WithSignByParent(caCert, caKey))
+	if err != nil {
+		t.Fatalf("GenerateTLS() client error = %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		certificates []tls.Certificate
+		wantErr      bool
+	}{
+		{
+			name:         "with client certificate",
+			certificates: []tls.Certificate{clientCert},
+		},
+		{
+			name:    "without client certificate",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ln, err := tls.Listen("tcp", "127.0.0.1:0", serverConfig)
+			if err != nil {
+				t.Fatalf("tls.Listen() error = %v", err)
+			}
+			defer ln.Close()
+
+			peer := make(chan *x509.Certificate, 1)
+			go func() {
+				defer close(peer)
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+
+				tlsConn := conn.(*tls.Conn)
+				if err := tlsConn.Handshake(); err != nil {
+					return
+				}
+
+				peer <- tlsConn.ConnectionState().PeerCertificates[0]
+				conn.Write([]byte("ok"))
+			}()
+
+			config := clientConfig.Clone()
+			config.Certificates = tt.certificates
+			conn, err := tls.Dial("tcp", ln.Addr().String(), config)
+			var got []byte
+			if err == nil {
+				// with TLS 1.3 a rejected client certificate is reported on the first read
+				got, err = io.ReadAll(conn)
+				conn.Close()
+			}
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("mTLS handshake error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			peerCert := <-peer
+			if tt.wantErr {
+				return
+			}
+
+			if string(got) != "ok" {
+				t.Errorf("read %q, want %q", got, "ok")
+			}
+
+			if peerCert == nil || !peerCert.Equal(clientCert.Leaf) {
+				t.Errorf("server peer certificate does not match the client certificate")
+			}
+		})
+	}
+}